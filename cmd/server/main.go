@@ -1,44 +1,320 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
 	"github.com/99designs/gqlgen/graphql/playground"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/vektah/gqlparser/v2/ast"
 
 	"posts-comments-1/graph"
+	"posts-comments-1/graph/pubsub"
+	"posts-comments-1/internal/auth"
+	"posts-comments-1/internal/config"
+	"posts-comments-1/internal/domain"
+	"posts-comments-1/internal/filter"
+	"posts-comments-1/internal/health"
+	"posts-comments-1/internal/logging"
+	"posts-comments-1/internal/metrics"
+	"posts-comments-1/internal/migrate"
+	"posts-comments-1/internal/persisted"
+	"posts-comments-1/internal/ratelimit"
+	"posts-comments-1/internal/storage"
 	"posts-comments-1/internal/storage/memory"
 	pg "posts-comments-1/internal/storage/postgres"
+	"posts-comments-1/internal/tracing"
+	"posts-comments-1/migrations"
 )
 
 func main() {
-	storageType := os.Getenv("STORAGE_TYPE")
+	cfg, args, err := config.Load(os.Args[1:])
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		fatal("load config", err)
+	}
+
+	logger := logging.New(os.Stderr, cfg.Log)
+	slog.SetDefault(logger)
 
-	var resolver graph.Resolver
-	if storageType == "postgres" {
-		pgs, err := pg.New()
+	if len(args) > 0 {
+		switch args[0] {
+		case "migrate":
+			err = runMigrate(cfg.Postgres, args[1:])
+		case "manifest":
+			err = runManifest(args[1:])
+		default:
+			err = fmt.Errorf("unknown command %q", args[0])
+		}
 		if err != nil {
-			log.Fatal(err)
+			fatal(args[0], err)
+		}
+		return
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		fatal("set up tracing", err)
+	}
+
+	var (
+		resolver     graph.Resolver
+		rateStore    ratelimit.Store
+		closeStorage = func() {}
+		checker      = health.New(cfg.Server.HealthCheckTimeout)
+		registry     = prometheus.NewRegistry()
+	)
+	registry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+
+	if cfg.StorageType == "postgres" {
+		pgs, err := pg.New(cfg.Postgres)
+		if err != nil {
+			fatal("open storage", err)
+		}
+		if cfg.Features.AutoMigrate {
+			if err := autoMigrate(context.Background(), pgs); err != nil {
+				fatal("apply migrations", err)
+			}
 		}
 		resolver = graph.Resolver{Storage: pgs}
+		closeStorage = pgs.Close
+		if cfg.Features.Metrics {
+			metrics.RegisterPool(registry, pgs.Pool())
+		}
+
+		migrator, err := migrate.New(pgs.Pool(), migrations.FS)
+		if err != nil {
+			fatal("load migrations", err)
+		}
+		checker.Add("migrations", func(ctx context.Context) error {
+			n, err := migrator.Pending(ctx)
+			if err == nil && n > 0 {
+				err = fmt.Errorf("%d pending migrations", n)
+			}
+			return err
+		})
+		if cfg.PubSub.Backend != "memory" {
+			resolver.PubSub = pubsub.NewPostgres(pgs.Pool(), pubsub.DefaultChannel, pubsubOptions(cfg.PubSub))
+		}
+		if cfg.RateLimit.Store != "memory" {
+			rateStore = ratelimit.NewPostgres(pgs.Pool())
+		}
 	} else {
 		resolver = graph.Resolver{Storage: memory.New()}
 	}
+	if resolver.PubSub == nil {
+		resolver.PubSub = pubsub.NewMemory(pubsubOptions(cfg.PubSub))
+	}
+	if rateStore == nil {
+		rateStore = ratelimit.NewMemory()
+	}
+	if hc, ok := resolver.Storage.(storage.HealthChecker); ok {
+		checker.Add("storage", hc.Ping)
+	}
+	if cfg.Features.Metrics {
+		resolver.Storage = metrics.NewStorage(resolver.Storage, registry)
+		metrics.RegisterPubSub(registry, resolver.PubSub)
+	}
+	if resolver.Filters, err = contentFilters(cfg.ContentFilters, resolver.Storage); err != nil {
+		fatal("set up content filters", err)
+	}
+
+	authn, err := auth.New(auth.Config(cfg.Auth))
+	if err != nil {
+		fatal("set up authentication", err)
+	}
+
+	srv := handler.New(graph.NewSchema(&resolver))
+	srv.AddTransport(transport.Websocket{
+		KeepAlivePingInterval: cfg.Server.WebsocketKeepAlive,
+		InitFunc:              authn.WebsocketInit,
+	})
+	srv.AddTransport(transport.Options{})
+	srv.AddTransport(transport.GET{})
+	srv.AddTransport(transport.POST{})
+	srv.AddTransport(transport.MultipartForm{})
 
-	srv := handler.NewDefaultServer(graph.NewExecutableSchema(graph.Config{
-		Resolvers: &resolver,
-	}))
+	srv.SetQueryCache(lru.New[*ast.QueryDocument](1000))
+
+	if cfg.Features.Introspection {
+		srv.Use(extension.Introspection{})
+	}
+	if cfg.PersistedQueries.Manifest != "" {
+		manifest, err := persisted.LoadManifest(cfg.PersistedQueries.Manifest)
+		if err != nil {
+			fatal("load persisted queries", err)
+		}
+		srv.Use(persisted.Allowlist{Manifest: manifest})
+		slog.Info("only persisted queries are allowed", "operations", len(manifest))
+	} else {
+		srv.Use(extension.AutomaticPersistedQuery{
+			Cache: lru.New[string](100),
+		})
+	}
+	srv.Use(&graph.Limits{
+		MaxDepth:      cfg.Limits.MaxDepth,
+		MaxComplexity: cfg.Limits.MaxComplexity,
+		MaxAliases:    cfg.Limits.MaxAliases,
+	})
+	srv.Use(graph.DataLoaders{Storage: resolver.Storage})
+	srv.Use(tracing.GraphQL{})
+	srv.Use(logging.GraphQL{RedactVariables: cfg.Log.RedactVariables})
+	if cfg.Features.Metrics {
+		srv.Use(metrics.NewGraphQL(registry))
+	}
+	if cfg.RateLimit.Enabled {
+		srv.Use(&ratelimit.Limiter{Store: rateStore, Limits: rateLimits(cfg.RateLimit)})
+	}
+	subscriptions := &graph.SubscriptionStatus{}
+	srv.Use(subscriptions)
+	srv.SetErrorPresenter(graph.ErrorPresenter)
 
 	mux := http.NewServeMux()
-	mux.Handle("/", playground.Handler("GraphQL playground", "/query"))
-	mux.Handle("/query", srv)
-	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		_, _ = w.Write([]byte("ok"))
+	if cfg.Features.Playground {
+		mux.Handle("/", playground.Handler("GraphQL playground", "/query"))
+	}
+	var handlers handlerTracker
+	query := ratelimit.Middleware(cfg.RateLimit.TrustForwardedFor, authn.Middleware(srv))
+	mux.Handle("/query", handlers.wrap(tracing.Middleware(query)))
+	if cfg.Features.Metrics {
+		mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	}
+	mux.Handle("/livez", health.LiveHandler())
+	mux.Handle("/health", health.LiveHandler())
+	mux.Handle("/readyz", checker.ReadyHandler())
+
+	// Request contexts derive from baseCtx; cancelling it closes the
+	// websocket connections left after shutdown with the reason below.
+	baseCtx, cancelBase := context.WithCancel(transport.AppendCloseReason(context.Background(), "server is shutting down"))
+	defer cancelBase()
+
+	server := &http.Server{
+		Addr:              cfg.Server.ListenAddr,
+		Handler:           logging.Middleware(logger, mux),
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		BaseContext:       func(net.Listener) context.Context { return baseCtx },
+	}
+	server.RegisterOnShutdown(func() {
+		// Ends every subscription with a SHUTTING_DOWN error, so clients
+		// reconnect to another instance.
+		if err := resolver.PubSub.Close(); err != nil {
+			slog.Error("close pubsub", "error", err)
+		}
 	})
 
-	log.Println("server started on :8080")
-	log.Fatal(http.ListenAndServe(":8080", mux))
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errc := make(chan error, 1)
+	go func() { errc <- server.ListenAndServe() }()
+	slog.Info("server started", "addr", cfg.Server.ListenAddr, "storage", cfg.StorageType)
+
+	select {
+	case err := <-errc:
+		fatal("serve", err)
+	case <-ctx.Done():
+		stop()
+	}
+
+	checker.Drain()
+	slog.Info("shutting down, waiting for in-flight requests", "timeout", cfg.Server.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Error("shutdown", "error", err)
+	}
+	if err := subscriptions.Drain(shutdownCtx); err != nil {
+		slog.Error("waiting for subscriptions", "error", err)
+	}
+	cancelBase()
+	if err := handlers.wait(shutdownCtx); err != nil {
+		slog.Error("waiting for websocket connections", "error", err)
+	}
+	closeStorage()
+
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFlush()
+	if err := shutdownTracing(flushCtx); err != nil {
+		slog.Error("flush traces", "error", err)
+	}
+	slog.Info("server stopped")
+}
+
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+func pubsubOptions(c config.PubSubConfig) pubsub.Options {
+	return pubsub.Options{BufferSize: c.BufferSize, Overflow: pubsub.Overflow(c.Overflow)}
+}
+
+func rateLimits(c config.RateLimitConfig) map[string]ratelimit.Limit {
+	limits := make(map[string]ratelimit.Limit, len(c.Mutations))
+	for name, r := range c.Mutations {
+		limits[name] = ratelimit.Limit(r)
+	}
+	return limits
+}
+
+// contentFilters builds the filter pipeline, leaving out the filters
+// turned off in c.
+func contentFilters(c config.ContentFiltersConfig, comments filter.RecentComments) (*filter.Pipeline, error) {
+	var p filter.Pipeline
+	if c.BannedWords.File != "" {
+		words, err := filter.LoadWords(c.BannedWords.File)
+		if err != nil {
+			return nil, err
+		}
+		f, err := filter.NewBannedWords(words, domain.FilterAction(c.BannedWords.Action))
+		if err != nil {
+			return nil, err
+		}
+		p.Filters = append(p.Filters, f)
+	}
+	if c.Links.Max >= 0 {
+		f, err := filter.NewLinks(c.Links.Max, domain.FilterAction(c.Links.Action))
+		if err != nil {
+			return nil, err
+		}
+		p.Filters = append(p.Filters, f)
+	}
+	if c.Shouting.MaxUpperRatio > 0 || c.Shouting.MaxRepeat > 0 {
+		f, err := filter.NewShouting(filter.ShoutingOptions{
+			MinLetters:    c.Shouting.MinLetters,
+			MaxUpperRatio: c.Shouting.MaxUpperRatio,
+			MaxRepeat:     c.Shouting.MaxRepeat,
+			Action:        domain.FilterAction(c.Shouting.Action),
+		})
+		if err != nil {
+			return nil, err
+		}
+		p.Filters = append(p.Filters, f)
+	}
+	if c.Duplicates.Window > 0 {
+		f, err := filter.NewDuplicates(comments, c.Duplicates.Window, domain.FilterAction(c.Duplicates.Action))
+		if err != nil {
+			return nil, err
+		}
+		p.Filters = append(p.Filters, f)
+	}
+	return &p, nil
 }
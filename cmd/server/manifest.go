@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/vektah/gqlparser/v2/ast"
+
+	"posts-comments-1/graph"
+	"posts-comments-1/internal/persisted"
+)
+
+const manifestUsage = "usage: server manifest [-o file] path..."
+
+// runManifest implements the manifest subcommand: it extracts the
+// operations of the .graphql files under the given paths into a persisted
+// query manifest and lists their hashes.
+func runManifest(args []string) error {
+	fset := flag.NewFlagSet("manifest", flag.ContinueOnError)
+	out := fset.String("o", "", "manifest file to write, stdout by default")
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+	if fset.NArg() == 0 {
+		return errors.New(manifestUsage)
+	}
+
+	var sources []*ast.Source
+	for _, root := range fset.Args() {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || (path != root && filepath.Ext(path) != ".graphql" && filepath.Ext(path) != ".gql") {
+				return nil
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			sources = append(sources, &ast.Source{Name: path, Input: string(data)})
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	schema := graph.NewExecutableSchema(graph.Config{}).Schema()
+	ops, err := persisted.Extract(schema, sources...)
+	if err != nil {
+		return err
+	}
+
+	// The listing goes to stderr when the manifest itself goes to stdout.
+	w, list := os.Stdout, os.Stderr
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w, list = f, os.Stdout
+	}
+	if err := persisted.NewManifest(ops).Write(w); err != nil {
+		return fmt.Errorf("write manifest: %w", err)
+	}
+
+	tw := tabwriter.NewWriter(list, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "OPERATION\tTYPE\tSHA256")
+	for _, op := range ops {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", op.Name, op.Type, op.Hash)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// handlerTracker waits for running handlers. Websocket handlers run for the
+// whole connection but their connections are hijacked, so
+// http.Server.Shutdown does not wait for them.
+type handlerTracker struct {
+	wg sync.WaitGroup
+}
+
+func (t *handlerTracker) wrap(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.wg.Add(1)
+		defer t.wg.Done()
+		h.ServeHTTP(w, r)
+	})
+}
+
+// wait blocks until every tracked handler has returned or ctx is done.
+func (t *handlerTracker) wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		t.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,127 @@
+package graph
+
+import (
+	"strconv"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/google/uuid"
+)
+
+// maxFieldComplexity caps the score of a single field, so multiplying page
+// sizes of nested lists cannot overflow.
+const maxFieldComplexity = 1 << 30
+
+// NewSchema returns the executable schema for r with complexity scoring:
+// list fields multiply the cost of their selection by the number of items
+// requested with limit, first or last, and fields marked with @cost in
+// the schema weigh accordingly.
+func NewSchema(r *Resolver) graphql.ExecutableSchema {
+	cfg := Config{Resolvers: r}
+
+	c := &cfg.Complexity
+	c.Query.Posts = func(child int, limit, _ int32) int {
+		return listComplexity(child, int(limit))
+	}
+	c.Query.PostsConnection = func(child int, first *int32, _ *string, last *int32, _ *string) int {
+		return listComplexity(child, pageSize(first, last, defaultPostsPage))
+	}
+	c.Query.Comments = func(child int, _ uuid.UUID, limit, _ int32) int {
+		return listComplexity(child, int(limit))
+	}
+	c.Query.CommentsConnection = func(child int, _ uuid.UUID, first *int32, _ *string, last *int32, _ *string) int {
+		return listComplexity(child, pageSize(first, last, defaultCommentsPage))
+	}
+	c.Query.ModerationQueue = func(child int, first *int32, _ *string) int {
+		return listComplexity(child, pageSize(first, nil, defaultFlagsPage))
+	}
+	c.Comment.Replies = func(child int, first *int32, _ *string) int {
+		return listComplexity(child, pageSize(first, nil, defaultRepliesPage))
+	}
+	c.Comment.Revisions = func(child int, first *int32, _ *string) int {
+		return listComplexity(child, pageSize(first, nil, defaultRevisionsPage))
+	}
+	c.Post.CommentTree = func(child int, maxDepth, repliesPerNode int32) int {
+		// The tree loads up to repliesPerNode^d comments on level d and the
+		// selection, nested replies included, is rendered for each of them.
+		return listComplexity(child, treeSize(int(maxDepth), int(repliesPerNode)))
+	}
+
+	es := NewExecutableSchema(cfg)
+	return &costSchema{ExecutableSchema: es, weights: costWeights(es)}
+}
+
+// costSchema adds the @cost weights of fields to the complexity computed
+// by the generated schema.
+type costSchema struct {
+	graphql.ExecutableSchema
+	weights map[string]int
+}
+
+func (s *costSchema) Complexity(typeName, field string, childComplexity int, args map[string]any) (int, bool) {
+	c, ok := s.ExecutableSchema.Complexity(typeName, field, childComplexity, args)
+	if !ok {
+		c = childComplexity + 1
+	}
+	if w, ok := s.weights[typeName+"."+field]; ok {
+		c += w - 1
+	}
+	return capComplexity(c), true
+}
+
+// costWeights collects the weights of @cost directives by "Type.field".
+func costWeights(es graphql.ExecutableSchema) map[string]int {
+	weights := make(map[string]int)
+	for _, def := range es.Schema().Types {
+		for _, f := range def.Fields {
+			d := f.Directives.ForName("cost")
+			if d == nil {
+				continue
+			}
+			if arg := d.Arguments.ForName("weight"); arg != nil {
+				if w, err := strconv.Atoi(arg.Value.Raw); err == nil {
+					weights[def.Name+"."+f.Name] = w
+				}
+			}
+		}
+	}
+	return weights
+}
+
+// listComplexity is the cost of a list field returning n items.
+func listComplexity(child, n int) int {
+	n = max(n, 1)
+	if child > maxFieldComplexity/n {
+		return maxFieldComplexity
+	}
+	return 1 + child*n
+}
+
+// pageSize is the number of items a connection returns for first or last,
+// or def without either.
+func pageSize(first, last *int32, def int) int {
+	switch {
+	case first != nil:
+		return int(*first)
+	case last != nil:
+		return int(*last)
+	}
+	return def
+}
+
+// treeSize is the largest number of comments a tree of maxDepth levels
+// with perNode replies per comment holds.
+func treeSize(maxDepth, perNode int) int {
+	total, level := 0, 1
+	for range max(maxDepth, 1) {
+		if level > maxFieldComplexity/max(perNode, 1) {
+			return maxFieldComplexity
+		}
+		level *= max(perNode, 1)
+		total += level
+	}
+	return capComplexity(total)
+}
+
+func capComplexity(c int) int {
+	return min(c, maxFieldComplexity)
+}
@@ -0,0 +1,101 @@
+package graph
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// loaderWait is how long a loader collects keys before fetching them,
+	// long enough for the sibling resolvers of a list to ask for theirs.
+	loaderWait = 2 * time.Millisecond
+	// loaderMaxBatch caps the keys of a single fetch; a full batch is
+	// fetched without waiting.
+	loaderMaxBatch = 500
+)
+
+// loader batches the keys requested by concurrent resolvers into a single
+// fetch and caches the results for its lifetime, which is one response.
+type loader[K comparable, V any] struct {
+	ctx   context.Context
+	fetch func(ctx context.Context, keys []K) (map[K]V, error)
+	// missing is returned for keys left out by fetch, along with the zero
+	// V; nil makes a missing key a zero value.
+	missing error
+
+	mu      sync.Mutex
+	batches map[K]*batch[K, V]
+	pending *batch[K, V]
+}
+
+type batch[K comparable, V any] struct {
+	keys   []K
+	done   chan struct{}
+	values map[K]V
+	err    error
+}
+
+// newLoader returns a loader running fetch with ctx, the context of the
+// response, so a fetch does not fail with the resolver that triggered it.
+func newLoader[K comparable, V any](ctx context.Context, missing error, fetch func(context.Context, []K) (map[K]V, error)) *loader[K, V] {
+	return &loader[K, V]{
+		ctx:     ctx,
+		fetch:   fetch,
+		missing: missing,
+		batches: make(map[K]*batch[K, V]),
+	}
+}
+
+// Load returns the value of key, waiting for the batch it joins.
+func (l *loader[K, V]) Load(ctx context.Context, key K) (V, error) {
+	l.mu.Lock()
+	b, ok := l.batches[key]
+	if !ok {
+		b = l.pending
+		if b == nil {
+			b = &batch[K, V]{done: make(chan struct{})}
+			l.pending = b
+			time.AfterFunc(loaderWait, func() { l.dispatch(b) })
+		}
+		b.keys = append(b.keys, key)
+		l.batches[key] = b
+		if len(b.keys) >= loaderMaxBatch {
+			l.pending = nil
+			go l.run(b)
+		}
+	}
+	l.mu.Unlock()
+
+	var zero V
+	select {
+	case <-b.done:
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+	if b.err != nil {
+		return zero, b.err
+	}
+	v, ok := b.values[key]
+	if !ok {
+		return zero, l.missing
+	}
+	return v, nil
+}
+
+// dispatch fetches b unless it was already fetched for being full.
+func (l *loader[K, V]) dispatch(b *batch[K, V]) {
+	l.mu.Lock()
+	if l.pending != b {
+		l.mu.Unlock()
+		return
+	}
+	l.pending = nil
+	l.mu.Unlock()
+	l.run(b)
+}
+
+func (l *loader[K, V]) run(b *batch[K, V]) {
+	b.values, b.err = l.fetch(l.ctx, b.keys)
+	close(b.done)
+}
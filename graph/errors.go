@@ -0,0 +1,27 @@
+package graph
+
+import (
+	"context"
+	"errors"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+
+	"posts-comments-1/internal/domain"
+)
+
+// ErrorPresenter exposes the kind of domain errors as extensions.code, so
+// clients can branch on NOT_FOUND / VALIDATION / FORBIDDEN instead of on
+// message strings.
+func ErrorPresenter(ctx context.Context, err error) *gqlerror.Error {
+	gqlErr := graphql.DefaultErrorPresenter(ctx, err)
+
+	var de *domain.Error
+	if errors.As(err, &de) {
+		if gqlErr.Extensions == nil {
+			gqlErr.Extensions = map[string]any{}
+		}
+		gqlErr.Extensions["code"] = string(de.Kind)
+	}
+	return gqlErr
+}
@@ -0,0 +1,35 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"posts-comments-1/internal/domain"
+)
+
+func TestErrorPresenter_DomainErrorCodes(t *testing.T) {
+	cases := []struct {
+		err  error
+		code string
+	}{
+		{domain.ErrPostNotFound, "NOT_FOUND"},
+		{fmt.Errorf("wrapped: %w", domain.ErrCommentTooLong), "VALIDATION"},
+		{domain.ErrCommentsDisabled, "FORBIDDEN"},
+	}
+
+	for _, tc := range cases {
+		got := ErrorPresenter(context.Background(), tc.err)
+		if got.Extensions["code"] != tc.code {
+			t.Fatalf("%v: expected code %q, got %v", tc.err, tc.code, got.Extensions["code"])
+		}
+	}
+}
+
+func TestErrorPresenter_OtherErrorsHaveNoCode(t *testing.T) {
+	got := ErrorPresenter(context.Background(), errors.New("boom"))
+	if _, ok := got.Extensions["code"]; ok {
+		t.Fatalf("expected no code, got %v", got.Extensions["code"])
+	}
+}
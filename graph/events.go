@@ -0,0 +1,96 @@
+package graph
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"posts-comments-1/graph/pubsub"
+	"posts-comments-1/internal/domain"
+	"posts-comments-1/internal/logging"
+)
+
+// publish hands ev to the pub/sub. The change is already stored, so it is
+// published even if the client goes away, and a delivery failure is logged
+// rather than failing the mutation.
+func (r *Resolver) publish(ctx context.Context, ev pubsub.Event) {
+	if err := r.PubSub.Publish(context.WithoutCancel(ctx), ev); err != nil {
+		logging.FromContext(ctx).ErrorContext(ctx, "publish event failed",
+			"kind", ev.Kind, "id", ev.ID, "error", err)
+	}
+}
+
+// forward converts events of a subscription into its payload type, loading
+// post or comment state that was left out of the event. Missed events and
+// the reason the subscription ended are passed on to
+// SubscriptionStatus.
+func forward[T any](ctx context.Context, sub *pubsub.Subscription, payload func(pubsub.Event) T, load func(context.Context, pubsub.Event) (pubsub.Event, error)) <-chan T {
+	st, _ := ctx.Value(subscriptionStateKey{}).(*subscriptionState)
+
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		defer func() { st.end(sub.Err()) }()
+
+		for ev := range sub.C {
+			st.addMissed(ev.Missed)
+
+			if load != nil {
+				var err error
+				if ev, err = load(ctx, ev); err != nil {
+					logging.FromContext(ctx).ErrorContext(ctx, "load event state failed",
+						"kind", ev.Kind, "id", ev.ID, "error", err)
+					continue
+				}
+			}
+
+			select {
+			case out <- payload(ev):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// withoutHidden passes on the comments of in that are not hidden.
+func withoutHidden(ctx context.Context, in <-chan *domain.Comment) <-chan *domain.Comment {
+	out := make(chan *domain.Comment)
+	go func() {
+		defer close(out)
+		for c := range in {
+			if c.Status == domain.StatusHidden {
+				continue
+			}
+			select {
+			case out <- c:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+func (r *Resolver) loadComment(ctx context.Context, ev pubsub.Event) (pubsub.Event, error) {
+	if ev.Comment != nil {
+		return ev, nil
+	}
+	c, err := r.Storage.GetComment(ctx, ev.ID)
+	ev.Comment = c
+	return ev, err
+}
+
+func (r *Resolver) loadPost(ctx context.Context, ev pubsub.Event) (pubsub.Event, error) {
+	if ev.Post != nil {
+		return ev, nil
+	}
+	p, err := r.Storage.GetPost(ctx, ev.ID)
+	ev.Post = p
+	return ev, err
+}
+
+func eventComment(ev pubsub.Event) *domain.Comment { return ev.Comment }
+func eventPost(ev pubsub.Event) *domain.Post       { return ev.Post }
+func eventID(ev pubsub.Event) uuid.UUID            { return ev.ID }
@@ -0,0 +1,107 @@
+package graph
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/google/uuid"
+
+	"posts-comments-1/internal/domain"
+	"posts-comments-1/internal/filter"
+	"posts-comments-1/internal/logging"
+)
+
+// filterComment runs c through the content filters, rewriting its content
+// and making a flagged comment pending. A rejection is recorded here, as
+// the comment is never stored; the other decisions returned are to be
+// applied with applyFilterDecisions once c is stored.
+func (r *Resolver) filterComment(ctx context.Context, c *domain.Comment, edit bool) ([]filter.Decision, error) {
+	if r.Filters == nil {
+		return nil, nil
+	}
+
+	res, err := r.Filters.Run(ctx, filter.Input{
+		CommentID: c.ID,
+		PostID:    c.PostID,
+		AuthorID:  c.AuthorID,
+		Content:   c.Content,
+		Edit:      edit,
+	})
+	if err != nil {
+		if n := len(res.Decisions); n > 0 && res.Decisions[n-1].Action == domain.FilterReject {
+			r.recordFilterDecisions(ctx, c, res.Decisions[n-1:])
+		}
+		return nil, err
+	}
+
+	c.Content = res.Content
+	if err := domain.ValidateCommentContent(c.Content); err != nil {
+		return nil, err
+	}
+	if res.Flagged() && c.Status == domain.StatusVisible {
+		c.Status = domain.StatusPending
+	}
+	return res.Decisions, nil
+}
+
+// recordFilterDecisions logs the decisions and stores them for auditing.
+// Failures are only logged: the decisions do not change the outcome of the
+// mutation.
+func (r *Resolver) recordFilterDecisions(ctx context.Context, c *domain.Comment, decisions []filter.Decision) {
+	if len(decisions) == 0 {
+		return
+	}
+
+	logger := logging.FromContext(ctx)
+	records := make([]domain.FilterDecision, 0, len(decisions))
+	for _, d := range decisions {
+		level := slog.LevelInfo
+		if d.Action == domain.FilterReject {
+			level = slog.LevelWarn
+		}
+		logger.Log(ctx, level, "content filter decision",
+			"filter", d.Filter, "action", d.Action, "reason", d.Reason,
+			"comment_id", c.ID, "post_id", c.PostID, "author_id", c.AuthorID)
+
+		records = append(records, domain.FilterDecision{
+			ID:        uuid.New(),
+			CommentID: c.ID,
+			PostID:    c.PostID,
+			AuthorID:  c.AuthorID,
+			Filter:    d.Filter,
+			Action:    d.Action,
+			Reason:    d.Reason,
+			CreatedAt: now(),
+		})
+	}
+	if err := r.Storage.RecordFilterDecisions(context.WithoutCancel(ctx), records); err != nil {
+		logger.ErrorContext(ctx, "record filter decisions failed", "comment_id", c.ID, "error", err)
+	}
+}
+
+// applyFilterDecisions records the decisions returned by filterComment
+// for the stored comment c and puts it in the moderation queue for the
+// flags among them. The comment is already stored, so failures are only
+// logged.
+func (r *Resolver) applyFilterDecisions(ctx context.Context, c *domain.Comment, decisions []filter.Decision) {
+	r.recordFilterDecisions(ctx, c, decisions)
+
+	for _, d := range decisions {
+		if d.Action != domain.FilterFlag {
+			continue
+		}
+		reason := d.Filter + ": " + d.Reason
+		if runes := []rune(reason); len(runes) > domain.MaxFlagReasonLength {
+			reason = string(runes[:domain.MaxFlagReasonLength])
+		}
+		f := domain.CommentFlag{ID: uuid.New(), CommentID: c.ID, Reason: reason, CreatedAt: now()}
+		if err := r.Storage.FlagComment(context.WithoutCancel(ctx), f); err != nil {
+			logging.FromContext(ctx).ErrorContext(ctx, "flag filtered comment failed",
+				"comment_id", c.ID, "filter", d.Filter, "error", err)
+			continue
+		}
+		if c.Status == domain.StatusVisible {
+			c.Status = domain.StatusPending
+		}
+	}
+}
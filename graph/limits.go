@@ -0,0 +1,113 @@
+package graph
+
+import (
+	"context"
+	"strings"
+
+	"github.com/99designs/gqlgen/complexity"
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// Error codes of operations rejected by Limits.
+const (
+	CodeQueryTooDeep    = "QUERY_TOO_DEEP"
+	CodeQueryTooComplex = "QUERY_TOO_COMPLEX"
+	CodeTooManyAliases  = "TOO_MANY_ALIASES"
+)
+
+func init() {
+	// Rejected operations are answered like invalid ones: 422 over HTTP.
+	for _, code := range []string{CodeQueryTooDeep, CodeQueryTooComplex, CodeTooManyAliases} {
+		errcode.RegisterErrorType(code, errcode.KindProtocol)
+	}
+}
+
+// Limits is a gqlgen extension rejecting operations that nest too deep,
+// use too many aliases or score above the complexity limit, before any
+// resolver runs. Complexity is scored by the schema, see NewSchema.
+// Introspection fields are not counted. A zero limit is not enforced.
+type Limits struct {
+	MaxDepth      int
+	MaxComplexity int
+	MaxAliases    int
+
+	es graphql.ExecutableSchema
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationContextMutator
+} = (*Limits)(nil)
+
+func (*Limits) ExtensionName() string {
+	return "Limits"
+}
+
+func (l *Limits) Validate(es graphql.ExecutableSchema) error {
+	l.es = es
+	return nil
+}
+
+func (l *Limits) MutateOperationContext(ctx context.Context, oc *graphql.OperationContext) *gqlerror.Error {
+	var s shape
+	l.measure(oc.Operation.SelectionSet, 1, &s)
+	if l.MaxDepth > 0 && s.depth > l.MaxDepth {
+		return limitError(CodeQueryTooDeep, l.MaxDepth, "query is nested deeper than %d levels", l.MaxDepth)
+	}
+	if l.MaxAliases > 0 && s.aliases > l.MaxAliases {
+		return limitError(CodeTooManyAliases, l.MaxAliases, "query uses more than %d aliases", l.MaxAliases)
+	}
+
+	if l.MaxComplexity > 0 {
+		if c := complexity.Calculate(l.es, oc.Operation, oc.Variables); c > l.MaxComplexity {
+			err := limitError(CodeQueryTooComplex, l.MaxComplexity, "query complexity %d exceeds the limit of %d", c, l.MaxComplexity)
+			err.Extensions["complexity"] = c
+			return err
+		}
+	}
+	return nil
+}
+
+// shape is the depth and alias count of a query.
+type shape struct {
+	depth   int
+	aliases int
+}
+
+// measure walks set, found at depth, into s. It stops as soon as a limit
+// is exceeded, so fragments spread many times cannot make the walk itself
+// expensive.
+func (l *Limits) measure(set ast.SelectionSet, depth int, s *shape) {
+	for _, sel := range set {
+		if (l.MaxDepth > 0 && s.depth > l.MaxDepth) || (l.MaxAliases > 0 && s.aliases > l.MaxAliases) {
+			return
+		}
+
+		switch sel := sel.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(sel.Name, "__") {
+				continue
+			}
+			if sel.Alias != "" && sel.Alias != sel.Name {
+				s.aliases++
+			}
+			s.depth = max(s.depth, depth)
+			l.measure(sel.SelectionSet, depth+1, s)
+		case *ast.InlineFragment:
+			l.measure(sel.SelectionSet, depth, s)
+		case *ast.FragmentSpread:
+			if sel.Definition != nil {
+				l.measure(sel.Definition.SelectionSet, depth, s)
+			}
+		}
+	}
+}
+
+func limitError(code string, limit int, format string, args ...any) *gqlerror.Error {
+	err := gqlerror.Errorf(format, args...)
+	err.Extensions = map[string]any{"code": code, "limit": limit}
+	return err
+}
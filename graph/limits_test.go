@@ -0,0 +1,116 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/google/uuid"
+)
+
+// query posts q to a server with limits l and returns the HTTP status and
+// the code of the first error, if any.
+func query(t *testing.T, l *Limits, q string) (int, string) {
+	t.Helper()
+	srv := handler.New(NewSchema(newTestResolver()))
+	srv.AddTransport(transport.POST{})
+	srv.Use(l)
+
+	body, _ := json.Marshal(map[string]string{"query": q})
+	req := httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+
+	var resp struct {
+		Errors []struct {
+			Extensions map[string]any
+		}
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
+	}
+	if len(resp.Errors) == 0 {
+		return rec.Code, ""
+	}
+	code, _ := resp.Errors[0].Extensions["code"].(string)
+	return rec.Code, code
+}
+
+func TestLimits_Depth(t *testing.T) {
+	l := &Limits{MaxDepth: 3}
+	if _, code := query(t, l, `{ postsConnection { edges { node { id } } } }`); code != CodeQueryTooDeep {
+		t.Fatalf("expected %s, got %q", CodeQueryTooDeep, code)
+	}
+	// Fragments count at the depth they are spread at.
+	q := `{ postsConnection { ...c } } fragment c on PostConnection { totalCount }`
+	if status, code := query(t, l, q); code != "" || status != http.StatusOK {
+		t.Fatalf("expected success, got %d %q", status, code)
+	}
+	// Introspection is not limited.
+	if _, code := query(t, l, `{ __schema { types { fields { type { ofType { name } } } } } }`); code != "" {
+		t.Fatalf("expected introspection to pass, got %q", code)
+	}
+}
+
+func TestLimits_Aliases(t *testing.T) {
+	l := &Limits{MaxAliases: 2}
+	q := `{ a: posts { id } b: posts { id } c: posts { id } }`
+	status, code := query(t, l, q)
+	if code != CodeTooManyAliases || status != http.StatusUnprocessableEntity {
+		t.Fatalf("expected %s with 422, got %d %q", CodeTooManyAliases, status, code)
+	}
+}
+
+func TestLimits_ComplexityFollowsPageSize(t *testing.T) {
+	l := &Limits{MaxComplexity: 150}
+	if _, code := query(t, l, `{ posts(limit: 10) { id title } }`); code != "" {
+		t.Fatalf("expected a small page to pass, got %q", code)
+	}
+	if _, code := query(t, l, `{ posts(limit: 500) { id title } }`); code != CodeQueryTooComplex {
+		t.Fatalf("expected %s, got %q", CodeQueryTooComplex, code)
+	}
+	if _, code := query(t, l, `{ postsConnection(first: 100) { edges { node { id } } } }`); code != CodeQueryTooComplex {
+		t.Fatalf("expected %s, got %q", CodeQueryTooComplex, code)
+	}
+}
+
+func TestLegacyLists_RejectLargeLimit(t *testing.T) {
+	r := newTestResolver()
+	ctx := context.Background()
+
+	if _, err := r.Query().Posts(ctx, maxPageSize, 0); err != nil {
+		t.Fatalf("expected limit %d to pass, got %v", maxPageSize, err)
+	}
+	if _, err := r.Query().Posts(ctx, maxPageSize+1, 0); !errors.Is(err, ErrInvalidPageSize) {
+		t.Fatalf("expected ErrInvalidPageSize, got %v", err)
+	}
+	if _, err := r.Query().Comments(ctx, uuid.New(), maxPageSize+1, 0); !errors.Is(err, ErrInvalidPageSize) {
+		t.Fatalf("expected ErrInvalidPageSize, got %v", err)
+	}
+}
+
+func TestNewSchema_CostDirective(t *testing.T) {
+	es := NewSchema(newTestResolver())
+
+	got, _ := es.Complexity("CommentConnection", "totalCount", 0, nil)
+	if got != 5 {
+		t.Fatalf("expected totalCount to cost 5, got %d", got)
+	}
+	// The selection of 2 for each of the 10+100+1000 comments loaded and
+	// 10 for the field itself.
+	got, _ = es.Complexity("Post", "commentTree", 2, map[string]any{"maxDepth": int64(3), "repliesPerNode": int64(10)})
+	if got != 2230 {
+		t.Fatalf("expected commentTree to cost 2230, got %d", got)
+	}
+	got, _ = es.Complexity("Query", "posts", 2, map[string]any{"limit": int64(100), "offset": int64(0)})
+	if got != 201 {
+		t.Fatalf("expected posts(limit: 100) to cost 201, got %d", got)
+	}
+}
@@ -0,0 +1,91 @@
+package graph
+
+import (
+	"context"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/google/uuid"
+
+	"posts-comments-1/internal/domain"
+	"posts-comments-1/internal/storage"
+)
+
+// loaders batches the lookups of nested fields, so that a list of comments
+// costs one storage call per field rather than one per comment.
+type loaders struct {
+	posts         *loader[uuid.UUID, *domain.Post]
+	comments      *loader[uuid.UUID, *domain.Comment]
+	users         *loader[uuid.UUID, *domain.User]
+	commentCounts *loader[uuid.UUID, int]
+	replyCounts   *loader[uuid.UUID, int]
+}
+
+func newLoaders(ctx context.Context, s storage.Storage) *loaders {
+	// Loaders live for one response, so the role of its user is fixed.
+	includeHidden := isModerator(ctx)
+	return &loaders{
+		posts: newLoader(ctx, domain.ErrPostNotFound, func(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]*domain.Post, error) {
+			posts, err := s.GetPostsByIDs(ctx, ids)
+			return byID(posts, func(p *domain.Post) uuid.UUID { return p.ID }), err
+		}),
+		comments: newLoader(ctx, domain.ErrCommentNotFound, func(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]*domain.Comment, error) {
+			comments, err := s.GetCommentsByIDs(ctx, ids)
+			return byID(comments, func(c *domain.Comment) uuid.UUID { return c.ID }), err
+		}),
+		// Authors may be missing: users are recorded on their first write.
+		users: newLoader(ctx, nil, func(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]*domain.User, error) {
+			users, err := s.GetUsersByIDs(ctx, ids)
+			return byID(users, func(u *domain.User) uuid.UUID { return u.ID }), err
+		}),
+		commentCounts: newLoader(ctx, nil, func(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]int, error) {
+			return s.CountCommentsByPostIDs(ctx, ids, includeHidden)
+		}),
+		replyCounts: newLoader(ctx, nil, func(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]int, error) {
+			return s.CountRepliesByCommentIDs(ctx, ids, includeHidden)
+		}),
+	}
+}
+
+func byID[T any](items []T, id func(*T) uuid.UUID) map[uuid.UUID]*T {
+	m := make(map[uuid.UUID]*T, len(items))
+	for i := range items {
+		m[id(&items[i])] = &items[i]
+	}
+	return m
+}
+
+type loadersKey struct{}
+
+// DataLoaders is a gqlgen extension giving every response, including every
+// event of a subscription, its own loaders, so cached values never outlive
+// the response.
+type DataLoaders struct {
+	Storage storage.Storage
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.ResponseInterceptor
+} = DataLoaders{}
+
+func (DataLoaders) ExtensionName() string {
+	return "DataLoaders"
+}
+
+func (DataLoaders) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+func (d DataLoaders) InterceptResponse(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	return next(context.WithValue(ctx, loadersKey{}, newLoaders(ctx, d.Storage)))
+}
+
+// loaders returns the loaders of the response. Without the DataLoaders
+// extension, as when resolvers are called directly, every call gets fresh
+// loaders and nothing is batched.
+func (r *Resolver) loaders(ctx context.Context) *loaders {
+	if l, ok := ctx.Value(loadersKey{}).(*loaders); ok {
+		return l
+	}
+	return newLoaders(ctx, r.Storage)
+}
@@ -0,0 +1,119 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/google/uuid"
+
+	"posts-comments-1/graph/model"
+	"posts-comments-1/internal/domain"
+	"posts-comments-1/internal/storage"
+)
+
+// countingStorage counts the batch lookups of the wrapped storage.
+type countingStorage struct {
+	storage.Storage
+
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (s *countingStorage) count(method string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls[method]++
+}
+
+func (s *countingStorage) GetPostsByIDs(ctx context.Context, ids []uuid.UUID) ([]domain.Post, error) {
+	s.count("GetPostsByIDs")
+	return s.Storage.GetPostsByIDs(ctx, ids)
+}
+
+func (s *countingStorage) GetCommentsByIDs(ctx context.Context, ids []uuid.UUID) ([]domain.Comment, error) {
+	s.count("GetCommentsByIDs")
+	return s.Storage.GetCommentsByIDs(ctx, ids)
+}
+
+func (s *countingStorage) GetUsersByIDs(ctx context.Context, ids []uuid.UUID) ([]domain.User, error) {
+	s.count("GetUsersByIDs")
+	return s.Storage.GetUsersByIDs(ctx, ids)
+}
+
+func (s *countingStorage) CountRepliesByCommentIDs(ctx context.Context, ids []uuid.UUID, includeHidden bool) (map[uuid.UUID]int, error) {
+	s.count("CountRepliesByCommentIDs")
+	return s.Storage.CountRepliesByCommentIDs(ctx, ids, includeHidden)
+}
+
+func TestDataLoaders_BatchCommentFields(t *testing.T) {
+	r := newTestResolver()
+	p, err := r.Mutation().CreatePost(asUser("alice"), model.CreatePostInput{Title: "t", Content: "c", CommentsAllowed: true})
+	if err != nil {
+		t.Fatalf("CreatePost error: %v", err)
+	}
+	root, err := r.Mutation().CreateComment(asUser("bob"), model.CreateCommentInput{PostID: p.ID, Content: "root"})
+	if err != nil {
+		t.Fatalf("CreateComment error: %v", err)
+	}
+	for i := range 10 {
+		ctx := asUser("user" + string(rune('a'+i)))
+		if _, err := r.Mutation().CreateComment(ctx, model.CreateCommentInput{PostID: p.ID, ParentID: &root.ID, Content: "reply"}); err != nil {
+			t.Fatalf("CreateComment error: %v", err)
+		}
+	}
+
+	counting := &countingStorage{Storage: r.Storage, calls: map[string]int{}}
+	r.Storage = counting
+	srv := handler.New(NewSchema(r))
+	srv.AddTransport(transport.POST{})
+	srv.Use(DataLoaders{Storage: counting})
+
+	q := `query($id: UUID!) { commentsConnection(postID: $id) { edges { node {
+		post { title commentCount } parent { id } author { name } replyCount
+	} } } }`
+	body, _ := json.Marshal(map[string]any{"query": q, "variables": map[string]any{"id": p.ID}})
+	req := httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+
+	var resp struct {
+		Data struct {
+			CommentsConnection struct {
+				Edges []struct {
+					Node struct {
+						Post struct {
+							CommentCount int
+						}
+						Author struct {
+							Name string
+						}
+					}
+				}
+			}
+		}
+		Errors []any
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
+	}
+	edges := resp.Data.CommentsConnection.Edges
+	if len(resp.Errors) > 0 || len(edges) != 11 {
+		t.Fatalf("unexpected response %s", rec.Body)
+	}
+	if edges[0].Node.Post.CommentCount != 11 || edges[0].Node.Author.Name != "bob" {
+		t.Fatalf("unexpected comment %+v", edges[0].Node)
+	}
+	for _, method := range []string{"GetPostsByIDs", "GetCommentsByIDs", "GetUsersByIDs", "CountRepliesByCommentIDs"} {
+		if n := counting.calls[method]; n != 1 {
+			t.Fatalf("expected 1 %s call, got %d", method, n)
+		}
+	}
+}
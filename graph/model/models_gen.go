@@ -3,9 +3,50 @@
 package model
 
 import (
+	"posts-comments-1/internal/domain"
+
 	"github.com/google/uuid"
 )
 
+type CommentConnection struct {
+	Edges      []*CommentEdge `json:"edges"`
+	PageInfo   *PageInfo      `json:"pageInfo"`
+	TotalCount int32          `json:"totalCount"`
+}
+
+type CommentEdge struct {
+	Cursor string          `json:"cursor"`
+	Node   *domain.Comment `json:"node"`
+}
+
+type CommentFlagConnection struct {
+	Edges      []*CommentFlagEdge `json:"edges"`
+	PageInfo   *PageInfo          `json:"pageInfo"`
+	TotalCount int32              `json:"totalCount"`
+}
+
+type CommentFlagEdge struct {
+	Cursor string              `json:"cursor"`
+	Node   *domain.CommentFlag `json:"node"`
+}
+
+type CommentRevisionConnection struct {
+	Edges      []*CommentRevisionEdge `json:"edges"`
+	PageInfo   *PageInfo              `json:"pageInfo"`
+	TotalCount int32                  `json:"totalCount"`
+}
+
+type CommentRevisionEdge struct {
+	Cursor string                  `json:"cursor"`
+	Node   *domain.CommentRevision `json:"node"`
+}
+
+type CommentTreeNode struct {
+	Comment *domain.Comment    `json:"comment"`
+	Depth   int32              `json:"depth"`
+	Replies []*CommentTreeNode `json:"replies"`
+}
+
 type CreateCommentInput struct {
 	PostID   uuid.UUID  `json:"postID"`
 	ParentID *uuid.UUID `json:"parentID,omitempty"`
@@ -21,8 +62,39 @@ type CreatePostInput struct {
 type Mutation struct {
 }
 
+type PageInfo struct {
+	HasNextPage     bool    `json:"hasNextPage"`
+	HasPreviousPage bool    `json:"hasPreviousPage"`
+	StartCursor     *string `json:"startCursor,omitempty"`
+	EndCursor       *string `json:"endCursor,omitempty"`
+}
+
+type PostConnection struct {
+	Edges      []*PostEdge `json:"edges"`
+	PageInfo   *PageInfo   `json:"pageInfo"`
+	TotalCount int32       `json:"totalCount"`
+}
+
+type PostEdge struct {
+	Cursor string       `json:"cursor"`
+	Node   *domain.Post `json:"node"`
+}
+
 type Query struct {
 }
 
 type Subscription struct {
 }
+
+type UpdateCommentInput struct {
+	ID      uuid.UUID `json:"id"`
+	Content string    `json:"content"`
+}
+
+// Fields left null keep their current value.
+type UpdatePostInput struct {
+	ID              uuid.UUID `json:"id"`
+	Title           *string   `json:"title,omitempty"`
+	Content         *string   `json:"content,omitempty"`
+	CommentsAllowed *bool     `json:"commentsAllowed,omitempty"`
+}
@@ -0,0 +1,149 @@
+package graph
+
+import (
+	"posts-comments-1/graph/model"
+	"posts-comments-1/internal/domain"
+	"posts-comments-1/internal/storage"
+)
+
+const (
+	maxPageSize = 100
+
+	// Page sizes of connections requested without first or last.
+	defaultPostsPage     = 20
+	defaultCommentsPage  = 50
+	defaultRepliesPage   = 20
+	defaultRevisionsPage = 20
+	defaultFlagsPage     = 20
+)
+
+var (
+	ErrInvalidPageSize  = domain.NewError(domain.KindValidation, "first and last must be between 0 and 100")
+	ErrFirstAndLastUsed = domain.NewError(domain.KindValidation, "first and last cannot be used together")
+)
+
+// legacyLimit checks the limit of the offset based posts and comments lists,
+// which is bounded by maxPageSize like connection pages.
+func legacyLimit(limit int32) (int, error) {
+	if limit < 0 || limit > maxPageSize {
+		return 0, ErrInvalidPageSize
+	}
+	return int(limit), nil
+}
+
+// pageParams converts Relay connection arguments into storage page params.
+// Without first and last the page holds the first defaultSize items.
+func pageParams(first *int32, after *string, last *int32, before *string, defaultSize int) (storage.PageParams, error) {
+	var p storage.PageParams
+
+	if first != nil && last != nil {
+		return p, ErrFirstAndLastUsed
+	}
+	switch {
+	case first != nil:
+		p.First = int(*first)
+	case last != nil:
+		p.Last = int(*last)
+	default:
+		p.First = defaultSize
+	}
+	if p.First < 0 || p.First > maxPageSize || p.Last < 0 || p.Last > maxPageSize {
+		return p, ErrInvalidPageSize
+	}
+	if after != nil {
+		c, err := storage.DecodeCursor(*after)
+		if err != nil {
+			return p, err
+		}
+		p.After = &c
+	}
+	if before != nil {
+		c, err := storage.DecodeCursor(*before)
+		if err != nil {
+			return p, err
+		}
+		p.Before = &c
+	}
+	return p, nil
+}
+
+func pageInfo(hasNext, hasPrev bool, start, end storage.Cursor, empty bool) *model.PageInfo {
+	info := &model.PageInfo{HasNextPage: hasNext, HasPreviousPage: hasPrev}
+	if !empty {
+		s, e := start.Encode(), end.Encode()
+		info.StartCursor, info.EndCursor = &s, &e
+	}
+	return info
+}
+
+func postConnection(page *storage.PostPage) *model.PostConnection {
+	edges := make([]*model.PostEdge, 0, len(page.Posts))
+	for i := range page.Posts {
+		p := page.Posts[i]
+		edges = append(edges, &model.PostEdge{Cursor: storage.PostCursor(p).Encode(), Node: &p})
+	}
+
+	var start, end storage.Cursor
+	if n := len(page.Posts); n > 0 {
+		start, end = storage.PostCursor(page.Posts[0]), storage.PostCursor(page.Posts[n-1])
+	}
+	return &model.PostConnection{
+		Edges:      edges,
+		PageInfo:   pageInfo(page.HasNextPage, page.HasPreviousPage, start, end, len(edges) == 0),
+		TotalCount: int32(page.TotalCount),
+	}
+}
+
+func commentConnection(page *storage.CommentPage) *model.CommentConnection {
+	edges := make([]*model.CommentEdge, 0, len(page.Comments))
+	for i := range page.Comments {
+		c := page.Comments[i]
+		edges = append(edges, &model.CommentEdge{Cursor: storage.CommentCursor(c).Encode(), Node: &c})
+	}
+
+	var start, end storage.Cursor
+	if n := len(page.Comments); n > 0 {
+		start, end = storage.CommentCursor(page.Comments[0]), storage.CommentCursor(page.Comments[n-1])
+	}
+	return &model.CommentConnection{
+		Edges:      edges,
+		PageInfo:   pageInfo(page.HasNextPage, page.HasPreviousPage, start, end, len(edges) == 0),
+		TotalCount: int32(page.TotalCount),
+	}
+}
+
+func revisionConnection(page *storage.RevisionPage) *model.CommentRevisionConnection {
+	edges := make([]*model.CommentRevisionEdge, 0, len(page.Revisions))
+	for i := range page.Revisions {
+		r := page.Revisions[i]
+		edges = append(edges, &model.CommentRevisionEdge{Cursor: storage.RevisionCursor(r).Encode(), Node: &r})
+	}
+
+	var start, end storage.Cursor
+	if n := len(page.Revisions); n > 0 {
+		start, end = storage.RevisionCursor(page.Revisions[0]), storage.RevisionCursor(page.Revisions[n-1])
+	}
+	return &model.CommentRevisionConnection{
+		Edges:      edges,
+		PageInfo:   pageInfo(page.HasNextPage, page.HasPreviousPage, start, end, len(edges) == 0),
+		TotalCount: int32(page.TotalCount),
+	}
+}
+
+func flagConnection(page *storage.FlagPage) *model.CommentFlagConnection {
+	edges := make([]*model.CommentFlagEdge, 0, len(page.Flags))
+	for i := range page.Flags {
+		f := page.Flags[i]
+		edges = append(edges, &model.CommentFlagEdge{Cursor: storage.FlagCursor(f).Encode(), Node: &f})
+	}
+
+	var start, end storage.Cursor
+	if n := len(page.Flags); n > 0 {
+		start, end = storage.FlagCursor(page.Flags[0]), storage.FlagCursor(page.Flags[n-1])
+	}
+	return &model.CommentFlagConnection{
+		Edges:      edges,
+		PageInfo:   pageInfo(page.HasNextPage, page.HasPreviousPage, start, end, len(edges) == 0),
+		TotalCount: int32(page.TotalCount),
+	}
+}
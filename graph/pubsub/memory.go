@@ -0,0 +1,277 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/google/uuid"
+)
+
+var ErrClosed = errors.New("pubsub is closed")
+
+// Memory is an in-process PubSub. Every subscriber has its own bounded
+// queue drained by its own goroutine, so publishing never waits for a
+// subscriber; what happens when a queue is full is decided by Options.Overflow.
+type Memory struct {
+	opts Options
+
+	mu          sync.RWMutex
+	subscribers map[Topic]map[int]*subscriber
+	nextSubID   int
+	closed      bool
+
+	dropped      atomic.Int64
+	disconnected atomic.Int64
+	lagging      atomic.Int64
+	active       atomic.Int64
+}
+
+func NewMemory(opts Options) *Memory {
+	return &Memory{
+		opts:        opts.withDefaults(),
+		subscribers: make(map[Topic]map[int]*subscriber),
+	}
+}
+
+func (m *Memory) Publish(ctx context.Context, ev Event) error {
+	m.mu.RLock()
+	var subs []*subscriber
+	for _, t := range ev.topics() {
+		for _, s := range m.subscribers[t] {
+			subs = append(subs, s)
+		}
+	}
+	m.mu.RUnlock()
+
+	for _, s := range subs {
+		s.offer(ev)
+	}
+	return nil
+}
+
+func (m *Memory) Subscribe(ctx context.Context, t Topic) (*Subscription, error) {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil, ErrClosed
+	}
+	m.nextSubID++
+	id := m.nextSubID
+
+	s := newSubscriber(m)
+	if m.subscribers[t] == nil {
+		m.subscribers[t] = make(map[int]*subscriber)
+	}
+	m.subscribers[t][id] = s
+	m.active.Add(1)
+	m.mu.Unlock()
+
+	go func() {
+		s.run(ctx)
+
+		m.mu.Lock()
+		if subs := m.subscribers[t]; subs != nil {
+			delete(subs, id)
+			if len(subs) == 0 {
+				delete(m.subscribers, t)
+			}
+		}
+		m.mu.Unlock()
+		m.active.Add(-1)
+	}()
+
+	return &Subscription{C: s.out, s: s}, nil
+}
+
+// Close ends every subscription.
+func (m *Memory) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.closed = true
+	for _, subs := range m.subscribers {
+		for _, s := range subs {
+			s.stop(ErrClosed)
+		}
+	}
+	return nil
+}
+
+// addMissed counts n events as missed by every current subscriber, to be
+// reported with the next event it receives. It is for events lost before
+// they reached Publish, whose number may be unknown.
+func (m *Memory) addMissed(n int) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, subs := range m.subscribers {
+		for _, s := range subs {
+			s.mu.Lock()
+			s.missed += n
+			s.mu.Unlock()
+		}
+	}
+}
+
+func (m *Memory) Subscribers(kind Kind) map[uuid.UUID]int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	out := make(map[uuid.UUID]int)
+	for t, subs := range m.subscribers {
+		if t.Kind == kind {
+			out[t.PostID] += len(subs)
+		}
+	}
+	return out
+}
+
+func (m *Memory) Options() Options {
+	return m.opts
+}
+
+func (m *Memory) Stats() Stats {
+	return Stats{
+		ActiveSubscribers:       m.active.Load(),
+		LaggingSubscribers:      m.lagging.Load(),
+		DroppedEvents:           m.dropped.Load(),
+		DisconnectedSubscribers: m.disconnected.Load(),
+	}
+}
+
+type subscriber struct {
+	m *Memory
+
+	mu      sync.Mutex
+	queue   []Event
+	missed  int
+	lagging bool
+	err     error
+
+	wake chan struct{}
+	out  chan Event
+}
+
+func newSubscriber(m *Memory) *subscriber {
+	return &subscriber{
+		m:    m,
+		wake: make(chan struct{}, 1),
+		out:  make(chan Event),
+	}
+}
+
+// offer queues ev without blocking, applying the overflow policy when the
+// queue is full.
+func (s *subscriber) offer(ev Event) {
+	s.mu.Lock()
+	if s.err != nil {
+		s.mu.Unlock()
+		return
+	}
+
+	if len(s.queue) >= s.m.opts.BufferSize {
+		if !s.lagging {
+			s.lagging = true
+			s.m.lagging.Add(1)
+		}
+
+		switch s.m.opts.Overflow {
+		case OverflowDisconnect:
+			s.mu.Unlock()
+			s.m.disconnected.Add(1)
+			s.stop(ErrSlowConsumer)
+			return
+		default:
+			// Keep the newest events: the one delivered next carries the
+			// number of events dropped before it.
+			s.queue = s.queue[1:]
+			s.missed++
+			s.m.dropped.Add(1)
+		}
+	}
+	s.queue = append(s.queue, ev)
+	s.mu.Unlock()
+
+	s.signal()
+}
+
+// stop ends the subscription with err once the consumer reads the queue up
+// to this point; pending events are discarded.
+func (s *subscriber) stop(err error) {
+	s.mu.Lock()
+	if s.err == nil {
+		s.err = err
+		s.queue = nil
+	}
+	s.mu.Unlock()
+
+	s.signal()
+}
+
+func (s *subscriber) signal() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *subscriber) next() (Event, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return Event{}, false, s.err
+	}
+	if len(s.queue) == 0 {
+		if s.lagging {
+			s.lagging = false
+			s.m.lagging.Add(-1)
+		}
+		return Event{}, false, nil
+	}
+
+	ev := s.queue[0]
+	s.queue = s.queue[1:]
+	ev.Missed, s.missed = s.missed, 0
+	return ev, true, nil
+}
+
+// run delivers queued events to out until ctx is done or the subscription
+// is stopped, then closes out.
+func (s *subscriber) run(ctx context.Context) {
+	defer close(s.out)
+	defer func() {
+		s.mu.Lock()
+		if s.lagging {
+			s.lagging = false
+			s.m.lagging.Add(-1)
+		}
+		if s.err == nil {
+			s.err = ctx.Err()
+		}
+		s.mu.Unlock()
+	}()
+
+	for {
+		ev, ok, err := s.next()
+		if err != nil {
+			return
+		}
+		if !ok {
+			select {
+			case <-s.wake:
+				continue
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		select {
+		case s.out <- ev:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package pubsub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func receive(t *testing.T, ch <-chan Event) Event {
+	t.Helper()
+	select {
+	case ev := <-ch:
+		return ev
+	case <-time.After(time.Second):
+		t.Fatal("no event")
+		return Event{}
+	}
+}
+
+func TestMemory_Topics(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ps := NewMemory(Options{})
+	postID := uuid.New()
+
+	byPost, err := ps.Subscribe(ctx, Topic{Kind: PostUpdated, PostID: postID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	all, err := ps.Subscribe(ctx, Topic{Kind: PostUpdated})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := ps.Subscribe(ctx, Topic{Kind: PostDeleted, PostID: postID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ev := Event{Kind: PostUpdated, PostID: postID, ID: postID}
+	if err := ps.Publish(ctx, ev); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := receive(t, byPost.C); got.ID != postID {
+		t.Fatalf("unexpected event %+v", got)
+	}
+	if got := receive(t, all.C); got.ID != postID {
+		t.Fatalf("unexpected event %+v", got)
+	}
+	select {
+	case got := <-other.C:
+		t.Fatalf("unexpected event for another kind: %+v", got)
+	default:
+	}
+}
+
+func TestMemory_CloseEndsSubscriptions(t *testing.T) {
+	ps := NewMemory(Options{})
+
+	sub, err := ps.Subscribe(context.Background(), Topic{Kind: CommentAdded, PostID: uuid.New()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ps.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := <-sub.C; ok {
+		t.Fatal("expected closed channel")
+	}
+	if err := sub.Err(); err != ErrClosed {
+		t.Fatalf("expected ErrClosed, got %v", err)
+	}
+	if _, err := ps.Subscribe(context.Background(), Topic{Kind: CommentAdded}); err != ErrClosed {
+		t.Fatalf("expected ErrClosed, got %v", err)
+	}
+}
+
+func TestMemory_OverflowCoalesce(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ps := NewMemory(Options{BufferSize: 2, Overflow: OverflowCoalesce})
+	postID := uuid.New()
+
+	sub, err := ps.Subscribe(ctx, Topic{Kind: CommentAdded, PostID: postID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first event may already be held by the delivery goroutine, so
+	// publish enough to overflow the buffer regardless.
+	ids := make([]uuid.UUID, 6)
+	for i := range ids {
+		ids[i] = uuid.New()
+		if err := ps.Publish(ctx, Event{Kind: CommentAdded, PostID: postID, ID: ids[i]}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var got []Event
+	for len(got) == 0 || got[len(got)-1].ID != ids[len(ids)-1] {
+		got = append(got, receive(t, sub.C))
+	}
+
+	missed := 0
+	for _, ev := range got {
+		missed += ev.Missed
+	}
+	if missed+len(got) != len(ids) {
+		t.Fatalf("expected %d events delivered or missed, got %d delivered and %d missed", len(ids), len(got), missed)
+	}
+	if missed == 0 {
+		t.Fatal("expected missed events")
+	}
+
+	stats := ps.Stats()
+	if stats.DroppedEvents != int64(missed) {
+		t.Fatalf("expected %d dropped events, got %d", missed, stats.DroppedEvents)
+	}
+	if stats.ActiveSubscribers != 1 {
+		t.Fatalf("expected 1 active subscriber, got %d", stats.ActiveSubscribers)
+	}
+}
+
+func TestMemory_OverflowDisconnect(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ps := NewMemory(Options{BufferSize: 1, Overflow: OverflowDisconnect})
+	postID := uuid.New()
+
+	sub, err := ps.Subscribe(ctx, Topic{Kind: CommentAdded, PostID: postID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 4 {
+		if err := ps.Publish(ctx, Event{Kind: CommentAdded, PostID: postID, ID: uuid.New()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	timeout := time.After(time.Second)
+	for done := false; !done; {
+		select {
+		case _, ok := <-sub.C:
+			done = !ok
+		case <-timeout:
+			t.Fatal("subscription was not closed")
+		}
+	}
+	if err := sub.Err(); err != ErrSlowConsumer {
+		t.Fatalf("expected ErrSlowConsumer, got %v", err)
+	}
+	if got := ps.Stats().DisconnectedSubscribers; got != 1 {
+		t.Fatalf("expected 1 disconnected subscriber, got %d", got)
+	}
+}
+
+func TestMemory_AddMissed(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ps := NewMemory(Options{})
+	postID := uuid.New()
+	sub, err := ps.Subscribe(ctx, Topic{Kind: CommentAdded, PostID: postID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ps.addMissed(1)
+	for _, want := range []int{1, 0} {
+		if err := ps.Publish(ctx, Event{Kind: CommentAdded, PostID: postID, ID: uuid.New()}); err != nil {
+			t.Fatal(err)
+		}
+		if got := receive(t, sub.C); got.Missed != want {
+			t.Fatalf("expected %d missed events, got %d", want, got.Missed)
+		}
+	}
+}
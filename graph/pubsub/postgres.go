@@ -0,0 +1,147 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	DefaultChannel = "posts_comments_events"
+
+	// maxPayload stays below the 8000 byte limit of NOTIFY payloads.
+	maxPayload = 7900
+
+	reconnectDelay = time.Second
+)
+
+// Postgres is a PubSub shared by all replicas connected to the same
+// database. Events are sent with NOTIFY and every replica, including the
+// publisher, delivers them to its local subscribers when they come back
+// through LISTEN.
+type Postgres struct {
+	pool    *pgxpool.Pool
+	channel string
+	local   *Memory
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewPostgres(pool *pgxpool.Pool, channel string, opts Options) *Postgres {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Postgres{
+		pool:    pool,
+		channel: channel,
+		local:   NewMemory(opts),
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	go p.listen(ctx)
+	return p
+}
+
+func (p *Postgres) Publish(ctx context.Context, ev Event) error {
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return fmt.Errorf("marshal event: %w", err)
+	}
+	if len(payload) > maxPayload {
+		// Too large for NOTIFY: send a reference, receivers load the state.
+		ev.Post, ev.Comment = nil, nil
+		if payload, err = json.Marshal(ev); err != nil {
+			return fmt.Errorf("marshal event: %w", err)
+		}
+	}
+
+	if _, err := p.pool.Exec(ctx, `SELECT pg_notify($1, $2);`, p.channel, string(payload)); err != nil {
+		return fmt.Errorf("notify: %w", err)
+	}
+	return nil
+}
+
+func (p *Postgres) Subscribe(ctx context.Context, t Topic) (*Subscription, error) {
+	return p.local.Subscribe(ctx, t)
+}
+
+func (p *Postgres) Subscribers(kind Kind) map[uuid.UUID]int {
+	return p.local.Subscribers(kind)
+}
+
+func (p *Postgres) Stats() Stats {
+	return p.local.Stats()
+}
+
+func (p *Postgres) Options() Options {
+	return p.local.Options()
+}
+
+// Close stops listening and ends every local subscription. It does not
+// close the pool, which belongs to the storage.
+func (p *Postgres) Close() error {
+	p.cancel()
+	<-p.done
+	return p.local.Close()
+}
+
+func (p *Postgres) listen(ctx context.Context) {
+	defer close(p.done)
+
+	for reconnect := false; ; reconnect = true {
+		err := p.listenOnce(ctx, reconnect)
+		if ctx.Err() != nil {
+			return
+		}
+		slog.Error("pubsub listen failed, reconnecting", "channel", p.channel, "error", err)
+
+		select {
+		case <-time.After(reconnectDelay):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// listenOnce listens on a new connection until it fails. Notifications
+// sent while no connection was listening are lost, so once LISTEN succeeds
+// the local subscribers, including those that joined before the first
+// connection, are told they missed events; how many is not known, so at
+// least one is reported.
+func (p *Postgres) listenOnce(ctx context.Context, reconnect bool) error {
+	pooled, err := p.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("acquire: %w", err)
+	}
+	// The connection is dedicated to LISTEN and must not go back to the
+	// pool with the subscription still active.
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{p.channel}.Sanitize()); err != nil {
+		return fmt.Errorf("listen: %w", err)
+	}
+	if reconnect {
+		slog.Warn("pubsub reconnected, events sent meanwhile are lost", "channel", p.channel)
+	}
+	p.local.addMissed(1)
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return fmt.Errorf("wait: %w", err)
+		}
+
+		var ev Event
+		if err := json.Unmarshal([]byte(n.Payload), &ev); err != nil {
+			slog.Warn("pubsub dropped malformed event", "channel", p.channel, "error", err)
+			continue
+		}
+		_ = p.local.Publish(ctx, ev)
+	}
+}
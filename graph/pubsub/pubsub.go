@@ -0,0 +1,131 @@
+// Package pubsub delivers change events from mutations to subscriptions,
+// either within one process or across replicas.
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"posts-comments-1/internal/domain"
+)
+
+type Kind string
+
+const (
+	CommentAdded   Kind = "commentAdded"
+	CommentUpdated Kind = "commentUpdated"
+	CommentDeleted Kind = "commentDeleted"
+	PostUpdated    Kind = "postUpdated"
+	PostDeleted    Kind = "postDeleted"
+)
+
+// Event is a change of a post or comment. ID is the ID of the changed post
+// or comment. Post and Comment carry the new state for non-delete events,
+// but may be left out when the event crosses process boundaries; receivers
+// then load the state by ID.
+type Event struct {
+	Kind    Kind            `json:"kind"`
+	PostID  uuid.UUID       `json:"postID"`
+	ID      uuid.UUID       `json:"id"`
+	Post    *domain.Post    `json:"post,omitempty"`
+	Comment *domain.Comment `json:"comment,omitempty"`
+
+	// Missed is set on delivered events only: the number of events the
+	// subscriber lost to overflow right before this one.
+	Missed int `json:"-"`
+}
+
+// Topic identifies a stream of events. A topic with a nil PostID receives
+// the events of its kind for every post.
+type Topic struct {
+	Kind   Kind
+	PostID uuid.UUID
+}
+
+func (e Event) topics() [2]Topic {
+	return [2]Topic{{e.Kind, e.PostID}, {e.Kind, uuid.Nil}}
+}
+
+type PubSub interface {
+	// Publish delivers ev to the subscribers of its topics. It never waits
+	// for subscribers to consume events.
+	Publish(ctx context.Context, ev Event) error
+	// Subscribe returns a subscription to t, which ends once ctx is done,
+	// the PubSub is closed or the subscriber falls too far behind.
+	Subscribe(ctx context.Context, t Topic) (*Subscription, error)
+	Close() error
+	// Subscribers counts the local subscribers of kind per post. Those
+	// subscribed to every post are counted under uuid.Nil.
+	Subscribers(kind Kind) map[uuid.UUID]int
+	Stats() Stats
+	// Options returns the buffering options of subscriptions, defaults
+	// applied.
+	Options() Options
+}
+
+var ErrSlowConsumer = errors.New("subscription closed: consumer is too slow")
+
+type Subscription struct {
+	// C is closed when the subscription ends.
+	C <-chan Event
+	s *subscriber
+}
+
+// Err returns why the subscription ended. It is only meaningful after C is
+// closed.
+func (s *Subscription) Err() error {
+	s.s.mu.Lock()
+	defer s.s.mu.Unlock()
+	return s.s.err
+}
+
+// Overflow is the policy applied when a subscriber's buffer is full.
+type Overflow string
+
+const (
+	// OverflowCoalesce drops the oldest buffered events and reports their
+	// number in Event.Missed of the next delivered event.
+	OverflowCoalesce Overflow = "coalesce"
+	// OverflowDisconnect ends the subscription with ErrSlowConsumer.
+	OverflowDisconnect Overflow = "disconnect"
+)
+
+func ParseOverflow(s string) (Overflow, error) {
+	switch o := Overflow(s); o {
+	case "":
+		return OverflowCoalesce, nil
+	case OverflowCoalesce, OverflowDisconnect:
+		return o, nil
+	}
+	return "", fmt.Errorf("unknown overflow policy %q", s)
+}
+
+const DefaultBufferSize = 64
+
+type Options struct {
+	// BufferSize is the number of events buffered per subscriber.
+	BufferSize int
+	Overflow   Overflow
+}
+
+func (o Options) withDefaults() Options {
+	if o.BufferSize <= 0 {
+		o.BufferSize = DefaultBufferSize
+	}
+	if o.Overflow == "" {
+		o.Overflow = OverflowCoalesce
+	}
+	return o
+}
+
+// Stats are counters for monitoring slow subscribers.
+type Stats struct {
+	ActiveSubscribers int64
+	// LaggingSubscribers have overflowed their buffer and not caught up yet.
+	LaggingSubscribers      int64
+	DroppedEvents           int64
+	DisconnectedSubscribers int64
+}
@@ -0,0 +1,161 @@
+package graph
+
+import (
+	"context"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"posts-comments-1/graph/pubsub"
+	"posts-comments-1/internal/domain"
+	"posts-comments-1/internal/logging"
+	"posts-comments-1/internal/storage"
+)
+
+// replayComments delivers the comments of postID created after since, in
+// creation order, and then the live comments. The subscription to live
+// comments must be made before calling it, so nothing created during the
+// replay is lost; live comments that were already replayed, or that the
+// client saw before since, are skipped.
+func (r *Resolver) replayComments(ctx context.Context, postID uuid.UUID, since storage.Cursor, live <-chan *domain.Comment) (<-chan *domain.Comment, error) {
+	// Live comments are held back until the replay is over, as many as
+	// the subscription buffer holds and with its overflow policy.
+	replayCtx, overflow := context.WithCancel(ctx)
+	release := holdComments(live, r.PubSub.Options(), overflow)
+
+	includeHidden := isModerator(ctx)
+	p := storage.PageParams{First: maxPageSize, After: &since}
+	page, err := r.Storage.GetCommentsPage(replayCtx, postID, p, includeHidden)
+	if err != nil {
+		release()
+		overflow()
+		return nil, err
+	}
+
+	st, _ := ctx.Value(subscriptionStateKey{}).(*subscriptionState)
+	out := make(chan *domain.Comment)
+	go func() {
+		defer close(out)
+		defer overflow()
+		defer func() {
+			if held := release(); held.err != nil {
+				st.end(held.err)
+			}
+		}()
+
+		send := func(c *domain.Comment) bool {
+			select {
+			case out <- c:
+				return true
+			case <-replayCtx.Done():
+				return false
+			}
+		}
+
+		replayed := make(map[uuid.UUID]struct{})
+		for {
+			for i := range page.Comments {
+				c := page.Comments[i]
+				replayed[c.ID] = struct{}{}
+				if !send(&c) {
+					return
+				}
+			}
+			if !page.HasNextPage || len(page.Comments) == 0 {
+				break
+			}
+
+			last := storage.CommentCursor(page.Comments[len(page.Comments)-1])
+			p.After = &last
+			if page, err = r.Storage.GetCommentsPage(replayCtx, postID, p, includeHidden); err != nil {
+				if replayCtx.Err() == nil {
+					logging.FromContext(ctx).ErrorContext(ctx, "replay comments failed",
+						"post_id", postID, "error", err)
+				}
+				return
+			}
+		}
+
+		sendLive := func(c *domain.Comment) bool {
+			if _, ok := replayed[c.ID]; ok {
+				delete(replayed, c.ID)
+				return true
+			}
+			if !since.Less(storage.CommentCursor(*c)) {
+				return true
+			}
+			return send(c)
+		}
+		held := release()
+		if held.err != nil {
+			st.end(held.err)
+			return
+		}
+		st.addMissed(held.missed)
+		for _, c := range held.comments {
+			if !sendLive(c) {
+				return
+			}
+		}
+		for c := range live {
+			if !sendLive(c) {
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// heldComments are the live comments held back during a replay.
+type heldComments struct {
+	comments []*domain.Comment
+	// missed counts the comments dropped with pubsub.OverflowCoalesce.
+	missed int
+	// err is pubsub.ErrSlowConsumer when the comments overflowed with
+	// pubsub.OverflowDisconnect.
+	err error
+}
+
+// holdComments reads in into a queue of up to opts.BufferSize comments
+// until the returned function is called, which stops reading and returns
+// the queue. When the queue is full, the oldest comment is dropped or,
+// with pubsub.OverflowDisconnect, reading stops and overflow is called.
+// Later calls return nothing.
+func holdComments(in <-chan *domain.Comment, opts pubsub.Options, overflow func()) func() heldComments {
+	stop := make(chan struct{})
+	done := make(chan heldComments, 1)
+	go func() {
+		var held heldComments
+		defer func() { done <- held }()
+		for {
+			select {
+			case c, ok := <-in:
+				if !ok {
+					return
+				}
+				if len(held.comments) >= opts.BufferSize {
+					if opts.Overflow == pubsub.OverflowDisconnect {
+						held.err = pubsub.ErrSlowConsumer
+						overflow()
+						return
+					}
+					held.comments = held.comments[1:]
+					held.missed++
+				}
+				held.comments = append(held.comments, c)
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() heldComments {
+		var held heldComments
+		once.Do(func() {
+			close(stop)
+			held = <-done
+		})
+		return held
+	}
+}
@@ -1,17 +1,115 @@
 package graph
 
 import (
-	"sync"
+	"context"
+	"time"
 
 	"github.com/google/uuid"
+	"posts-comments-1/graph/pubsub"
+	"posts-comments-1/internal/auth"
 	"posts-comments-1/internal/domain"
+	"posts-comments-1/internal/filter"
 	"posts-comments-1/internal/storage"
 )
 
 type Resolver struct {
 	Storage storage.Storage
+	PubSub  pubsub.PubSub
+	// Filters checks comment content before it is stored; nil lets every
+	// comment through.
+	Filters *filter.Pipeline
+}
+
+// now returns the current time with the microsecond precision of postgres
+// timestamps, so cursors built from freshly created objects stay valid.
+func now() time.Time {
+	return time.Now().Truncate(time.Microsecond)
+}
+
+// callerID records the authenticated caller as a user and returns its ID.
+// Anonymous callers get ErrUnauthenticated: whatever they wrote could never
+// be changed, as nobody would be its author.
+func (r *Resolver) callerID(ctx context.Context) (uuid.UUID, error) {
+	u, ok := auth.UserFromContext(ctx)
+	if !ok {
+		return uuid.Nil, domain.ErrUnauthenticated
+	}
+	if err := r.Storage.UpsertUser(ctx, u); err != nil {
+		return uuid.Nil, err
+	}
+	return u.ID, nil
+}
+
+// requireAuthor returns ErrUnauthenticated for anonymous callers and
+// ErrNotAuthor unless the caller is authorID.
+func requireAuthor(ctx context.Context, authorID uuid.UUID) error {
+	u, ok := auth.UserFromContext(ctx)
+	if !ok {
+		return domain.ErrUnauthenticated
+	}
+	if authorID == uuid.Nil || u.ID != authorID {
+		return domain.ErrNotAuthor
+	}
+	return nil
+}
+
+// requireAuthorOrModerator is requireAuthor letting moderators and admins
+// through as well.
+func requireAuthorOrModerator(ctx context.Context, authorID uuid.UUID) error {
+	u, ok := auth.UserFromContext(ctx)
+	if !ok {
+		return domain.ErrUnauthenticated
+	}
+	if isModerator(ctx) || (authorID != uuid.Nil && u.ID == authorID) {
+		return nil
+	}
+	return domain.ErrNotAuthorOrModerator
+}
+
+// requireAdmin returns ErrUnauthenticated for anonymous callers and
+// ErrNotAdmin for callers other than admins.
+func requireAdmin(ctx context.Context) error {
+	u, ok := auth.UserFromContext(ctx)
+	if !ok {
+		return domain.ErrUnauthenticated
+	}
+	if !u.HasRole(domain.RoleAdmin) {
+		return domain.ErrNotAdmin
+	}
+	return nil
+}
+
+// requireModerator returns ErrUnauthenticated for anonymous callers and
+// ErrNotModerator for callers other than moderators and admins.
+func requireModerator(ctx context.Context) error {
+	if _, ok := auth.UserFromContext(ctx); !ok {
+		return domain.ErrUnauthenticated
+	}
+	if !isModerator(ctx) {
+		return domain.ErrNotModerator
+	}
+	return nil
+}
+
+// isModerator reports whether the caller is a moderator or an admin.
+func isModerator(ctx context.Context) bool {
+	u, ok := auth.UserFromContext(ctx)
+	return ok && u.HasRole(domain.RoleModerator)
+}
+
+// user resolves an author ID; anonymous content has no author.
+func (r *Resolver) user(ctx context.Context, id uuid.UUID) (*domain.User, error) {
+	if id == uuid.Nil {
+		return nil, nil
+	}
+
+	return r.loaders(ctx).users.Load(ctx, id)
+}
 
-	mu          sync.Mutex
-	subscribers map[uuid.UUID]map[int]chan *domain.Comment
-	nextSubID   int
+// optionalID maps an omitted ID argument to uuid.Nil.
+func optionalID(id *uuid.UUID) uuid.UUID {
+	if id == nil {
+		return uuid.Nil
+	}
+	return *id
 }
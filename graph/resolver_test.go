@@ -0,0 +1,587 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/google/uuid"
+	"github.com/vektah/gqlparser/v2/ast"
+
+	"posts-comments-1/graph/model"
+	"posts-comments-1/graph/pubsub"
+	"posts-comments-1/internal/auth"
+	"posts-comments-1/internal/domain"
+	"posts-comments-1/internal/filter"
+	"posts-comments-1/internal/storage"
+	"posts-comments-1/internal/storage/memory"
+)
+
+func newTestResolver() *Resolver {
+	return &Resolver{Storage: memory.New(), PubSub: pubsub.NewMemory(pubsub.Options{})}
+}
+
+func asUser(name string) context.Context {
+	return auth.WithUser(context.Background(), domain.User{ID: uuid.New(), Name: name})
+}
+
+func strPtr(s string) *string { return &s }
+
+func TestCreate_RequiresAuthentication(t *testing.T) {
+	r := newTestResolver()
+	anon := context.Background()
+
+	if _, err := r.Mutation().CreatePost(anon, model.CreatePostInput{Title: "t", Content: "c", CommentsAllowed: true}); !errors.Is(err, domain.ErrUnauthenticated) {
+		t.Fatalf("expected ErrUnauthenticated, got %v", err)
+	}
+	p, err := r.Mutation().CreatePost(asUser("alice"), model.CreatePostInput{Title: "t", Content: "c", CommentsAllowed: true})
+	if err != nil {
+		t.Fatalf("CreatePost error: %v", err)
+	}
+	if _, err := r.Mutation().CreateComment(anon, model.CreateCommentInput{PostID: p.ID, Content: "hi"}); !errors.Is(err, domain.ErrUnauthenticated) {
+		t.Fatalf("expected ErrUnauthenticated, got %v", err)
+	}
+	if posts, _ := r.Storage.ListPosts(anon, 10, 0); len(posts) != 1 {
+		t.Fatalf("expected only the authenticated post to be stored, got %d", len(posts))
+	}
+}
+
+func TestUpdatePost_AuthorOnly(t *testing.T) {
+	r := newTestResolver()
+	alice, bob := asUser("alice"), asUser("bob")
+
+	p, err := r.Mutation().CreatePost(alice, model.CreatePostInput{Title: "t", Content: "c", CommentsAllowed: true})
+	if err != nil {
+		t.Fatalf("CreatePost error: %v", err)
+	}
+
+	input := model.UpdatePostInput{ID: p.ID, Title: strPtr("new")}
+	if _, err := r.Mutation().UpdatePost(context.Background(), input); !errors.Is(err, domain.ErrUnauthenticated) {
+		t.Fatalf("expected ErrUnauthenticated, got %v", err)
+	}
+	if _, err := r.Mutation().UpdatePost(bob, input); !errors.Is(err, domain.ErrNotAuthor) {
+		t.Fatalf("expected ErrNotAuthor, got %v", err)
+	}
+	if _, err := r.Mutation().SetCommentsAllowed(bob, p.ID, false); !errors.Is(err, domain.ErrNotAuthor) {
+		t.Fatalf("expected ErrNotAuthor, got %v", err)
+	}
+
+	got, err := r.Mutation().UpdatePost(alice, input)
+	if err != nil {
+		t.Fatalf("UpdatePost error: %v", err)
+	}
+	if got.Title != "new" || got.Content != "c" {
+		t.Fatalf("unexpected post after update: %+v", got)
+	}
+
+	if _, err := r.Mutation().UpdatePost(alice, model.UpdatePostInput{ID: p.ID, Title: strPtr("  ")}); !errors.Is(err, domain.ErrEmptyTitle) {
+		t.Fatalf("expected ErrEmptyTitle, got %v", err)
+	}
+}
+
+func TestUpdateComment_RecordsRevisions(t *testing.T) {
+	r := newTestResolver()
+	alice, bob := asUser("alice"), asUser("bob")
+	mod := auth.WithUser(context.Background(), domain.User{ID: uuid.New(), Name: "mod", Roles: []domain.Role{domain.RoleModerator}})
+
+	p, err := r.Mutation().CreatePost(alice, model.CreatePostInput{Title: "t", Content: "c", CommentsAllowed: true})
+	if err != nil {
+		t.Fatalf("CreatePost error: %v", err)
+	}
+	c, err := r.Mutation().CreateComment(alice, model.CreateCommentInput{PostID: p.ID, Content: "v1"})
+	if err != nil {
+		t.Fatalf("CreateComment error: %v", err)
+	}
+	if c.EditedAt != nil || c.RevisionCount != 0 {
+		t.Fatalf("expected a new comment to be unedited, got %+v", c)
+	}
+
+	for _, content := range []string{"v2", "v3"} {
+		c, err = r.Mutation().UpdateComment(alice, model.UpdateCommentInput{ID: c.ID, Content: content})
+		if err != nil {
+			t.Fatalf("UpdateComment error: %v", err)
+		}
+	}
+	if c.Content != "v3" || c.EditedAt == nil || c.RevisionCount != 2 {
+		t.Fatalf("unexpected comment after edits: %+v", c)
+	}
+
+	if _, err := r.Comment().Revisions(bob, c, nil, nil); !errors.Is(err, domain.ErrNotAuthorOrModerator) {
+		t.Fatalf("expected ErrNotAuthorOrModerator, got %v", err)
+	}
+	var conn *model.CommentRevisionConnection
+	for _, ctx := range []context.Context{alice, mod} {
+		conn, err = r.Comment().Revisions(ctx, c, nil, nil)
+		if err != nil {
+			t.Fatalf("Revisions error: %v", err)
+		}
+		if conn.TotalCount != 2 || len(conn.Edges) != 2 || conn.Edges[0].Node.Content != "v1" || conn.Edges[1].Node.Content != "v2" {
+			t.Fatalf("unexpected revisions %+v", conn.Edges)
+		}
+	}
+
+	first := int32(1)
+	conn, err = r.Comment().Revisions(alice, c, &first, &conn.Edges[0].Cursor)
+	if err != nil {
+		t.Fatalf("Revisions error: %v", err)
+	}
+	if len(conn.Edges) != 1 || conn.Edges[0].Node.Content != "v2" || conn.PageInfo.HasNextPage {
+		t.Fatalf("unexpected page after the first revision: %+v", conn.Edges)
+	}
+}
+
+func TestDeleteComment_PublishesEvent(t *testing.T) {
+	r := newTestResolver()
+	alice := asUser("alice")
+
+	p, err := r.Mutation().CreatePost(alice, model.CreatePostInput{Title: "t", Content: "c", CommentsAllowed: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := r.Mutation().CreateComment(alice, model.CreateCommentInput{PostID: p.ID, Content: "hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	deleted, err := r.Subscription().CommentDeleted(ctx, p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := r.Mutation().DeleteComment(asUser("bob"), c.ID); !errors.Is(err, domain.ErrNotAuthorOrModerator) {
+		t.Fatalf("expected ErrNotAuthorOrModerator, got %v", err)
+	}
+	if _, err := r.Mutation().DeleteComment(alice, c.ID); err != nil {
+		t.Fatalf("DeleteComment error: %v", err)
+	}
+
+	select {
+	case id := <-deleted:
+		if id != c.ID {
+			t.Fatalf("expected %v, got %v", c.ID, id)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no commentDeleted event")
+	}
+}
+
+func TestDeleteComment_TombstoneAndPurge(t *testing.T) {
+	r := newTestResolver()
+	alice := asUser("alice")
+	mod := auth.WithUser(context.Background(), domain.User{ID: uuid.New(), Name: "mod", Roles: []domain.Role{domain.RoleModerator}})
+	admin := auth.WithUser(context.Background(), domain.User{ID: uuid.New(), Name: "admin", Roles: []domain.Role{domain.RoleAdmin}})
+
+	p, err := r.Mutation().CreatePost(alice, model.CreatePostInput{Title: "t", Content: "c", CommentsAllowed: true})
+	if err != nil {
+		t.Fatalf("CreatePost error: %v", err)
+	}
+	c, err := r.Mutation().CreateComment(alice, model.CreateCommentInput{PostID: p.ID, Content: "rude"})
+	if err != nil {
+		t.Fatalf("CreateComment error: %v", err)
+	}
+	if _, err := r.Mutation().CreateComment(alice, model.CreateCommentInput{PostID: p.ID, ParentID: &c.ID, Content: "reply"}); err != nil {
+		t.Fatalf("CreateComment error: %v", err)
+	}
+
+	if _, err := r.Mutation().DeleteComment(mod, c.ID); err != nil {
+		t.Fatalf("DeleteComment error: %v", err)
+	}
+	c, err = r.Storage.GetComment(context.Background(), c.ID)
+	if err != nil {
+		t.Fatalf("GetComment error: %v", err)
+	}
+	if content, _ := r.Comment().Content(alice, c); content != domain.TombstoneContent {
+		t.Fatalf("expected the tombstone content, got %q", content)
+	}
+	if by, _ := r.Comment().DeletedBy(alice, c); by != nil {
+		t.Fatalf("expected deletedBy to be hidden from users, got %+v", by)
+	}
+	if by, _ := r.Comment().DeletedBy(mod, c); by == nil || by.Name != "mod" {
+		t.Fatalf("expected deletedBy to be the moderator, got %+v", by)
+	}
+
+	if _, err := r.Mutation().PurgeComment(mod, c.ID); !errors.Is(err, domain.ErrNotAdmin) {
+		t.Fatalf("expected ErrNotAdmin, got %v", err)
+	}
+	ids, err := r.Mutation().PurgeComment(admin, c.ID)
+	if err != nil {
+		t.Fatalf("PurgeComment error: %v", err)
+	}
+	if len(ids) != 2 {
+		t.Fatalf("expected the comment and its reply to be purged, got %v", ids)
+	}
+}
+
+func TestResolveFlag_HidesComment(t *testing.T) {
+	r := newTestResolver()
+	alice, bob := asUser("alice"), asUser("bob")
+	mod := auth.WithUser(context.Background(), domain.User{ID: uuid.New(), Name: "mod", Roles: []domain.Role{domain.RoleModerator}})
+
+	p, err := r.Mutation().CreatePost(alice, model.CreatePostInput{Title: "t", Content: "c", CommentsAllowed: true})
+	if err != nil {
+		t.Fatalf("CreatePost error: %v", err)
+	}
+	c, err := r.Mutation().CreateComment(alice, model.CreateCommentInput{PostID: p.ID, Content: "rude"})
+	if err != nil {
+		t.Fatalf("CreateComment error: %v", err)
+	}
+
+	if _, err := r.Mutation().FlagComment(context.Background(), c.ID, "rude"); !errors.Is(err, domain.ErrUnauthenticated) {
+		t.Fatalf("expected ErrUnauthenticated, got %v", err)
+	}
+	if _, err := r.Mutation().FlagComment(bob, c.ID, " "); !errors.Is(err, domain.ErrEmptyReason) {
+		t.Fatalf("expected ErrEmptyReason, got %v", err)
+	}
+	f, err := r.Mutation().FlagComment(bob, c.ID, "rude")
+	if err != nil {
+		t.Fatalf("FlagComment error: %v", err)
+	}
+
+	if _, err := r.Query().ModerationQueue(bob, nil, nil); !errors.Is(err, domain.ErrNotModerator) {
+		t.Fatalf("expected ErrNotModerator, got %v", err)
+	}
+	queue, err := r.Query().ModerationQueue(mod, nil, nil)
+	if err != nil {
+		t.Fatalf("ModerationQueue error: %v", err)
+	}
+	if len(queue.Edges) != 1 || queue.Edges[0].Node.ID != f.ID {
+		t.Fatalf("expected the flag in the queue, got %+v", queue.Edges)
+	}
+
+	if _, err := r.Mutation().ResolveFlag(bob, f.ID, domain.StatusHidden); !errors.Is(err, domain.ErrNotModerator) {
+		t.Fatalf("expected ErrNotModerator, got %v", err)
+	}
+	hidden, err := r.Mutation().ResolveFlag(mod, f.ID, domain.StatusHidden)
+	if err != nil {
+		t.Fatalf("ResolveFlag error: %v", err)
+	}
+	if content, _ := r.Comment().Content(bob, hidden); content != domain.HiddenContent {
+		t.Fatalf("expected the hidden content, got %q", content)
+	}
+	if content, _ := r.Comment().Content(mod, hidden); content != "rude" {
+		t.Fatalf("expected moderators to see the content, got %q", content)
+	}
+
+	if conn, _ := r.Query().CommentsConnection(bob, p.ID, nil, nil, nil, nil); len(conn.Edges) != 0 {
+		t.Fatalf("expected the hidden comment to be left out for users, got %d", len(conn.Edges))
+	}
+	if conn, _ := r.Query().CommentsConnection(mod, p.ID, nil, nil, nil, nil); len(conn.Edges) != 1 {
+		t.Fatalf("expected the hidden comment for moderators, got %d", len(conn.Edges))
+	}
+	if n, _ := r.Post().CommentCount(bob, p); n != 0 {
+		t.Fatalf("expected the comment count to leave out the hidden comment, got %d", n)
+	}
+	if n, _ := r.Post().CommentCount(mod, p); n != 1 {
+		t.Fatalf("expected the hidden comment counted for moderators, got %d", n)
+	}
+
+	ctx, cancel := context.WithCancel(bob)
+	defer cancel()
+	added, err := r.Subscription().CommentAdded(ctx, p.ID, nil)
+	if err != nil {
+		t.Fatalf("CommentAdded error: %v", err)
+	}
+	r.publish(context.Background(), pubsub.Event{Kind: pubsub.CommentAdded, PostID: p.ID, ID: hidden.ID, Comment: hidden})
+	live, err := r.Mutation().CreateComment(alice, model.CreateCommentInput{PostID: p.ID, Content: "fine"})
+	if err != nil {
+		t.Fatalf("CreateComment error: %v", err)
+	}
+	select {
+	case got := <-added:
+		if got.ID != live.ID {
+			t.Fatalf("expected the visible comment %v, got %v", live.ID, got.ID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no event")
+	}
+}
+
+func TestCreateComment_ContentFilters(t *testing.T) {
+	r := newTestResolver()
+	links, _ := filter.NewLinks(0, domain.FilterFlag)
+	dups, _ := filter.NewDuplicates(r.Storage, time.Minute, domain.FilterReject)
+	r.Filters = &filter.Pipeline{Filters: []filter.Filter{links, dups}}
+	alice := asUser("alice")
+	mod := auth.WithUser(context.Background(), domain.User{ID: uuid.New(), Name: "mod", Roles: []domain.Role{domain.RoleModerator}})
+
+	p, err := r.Mutation().CreatePost(alice, model.CreatePostInput{Title: "t", Content: "c", CommentsAllowed: true})
+	if err != nil {
+		t.Fatalf("CreatePost error: %v", err)
+	}
+	c, err := r.Mutation().CreateComment(alice, model.CreateCommentInput{PostID: p.ID, Content: "buy at www.example.com"})
+	if err != nil {
+		t.Fatalf("CreateComment error: %v", err)
+	}
+	if c.Status != domain.StatusPending {
+		t.Fatalf("expected a pending comment, got %q", c.Status)
+	}
+	queue, err := r.Query().ModerationQueue(mod, nil, nil)
+	if err != nil {
+		t.Fatalf("ModerationQueue error: %v", err)
+	}
+	if len(queue.Edges) != 1 || queue.Edges[0].Node.CommentID != c.ID || queue.Edges[0].Node.ReporterID != uuid.Nil {
+		t.Fatalf("expected a flag by the filter in the queue, got %+v", queue.Edges)
+	}
+
+	_, err = r.Mutation().CreateComment(alice, model.CreateCommentInput{PostID: p.ID, Content: " BUY at www.example.com"})
+	if domain.KindOf(err) != domain.KindValidation {
+		t.Fatalf("expected the duplicate to be rejected, got %v", err)
+	}
+	if _, err := r.Mutation().CreateComment(asUser("bob"), model.CreateCommentInput{PostID: p.ID, Content: "buy at www.example.com"}); err != nil {
+		t.Fatalf("expected the same comment by another author to pass, got %v", err)
+	}
+}
+
+// auditStorage keeps the filter decisions recorded through it.
+type auditStorage struct {
+	storage.Storage
+	decisions []domain.FilterDecision
+}
+
+func (s *auditStorage) RecordFilterDecisions(ctx context.Context, decisions []domain.FilterDecision) error {
+	s.decisions = append(s.decisions, decisions...)
+	return s.Storage.RecordFilterDecisions(ctx, decisions)
+}
+
+func TestCreateComment_RecordsStoredDecisionsOnly(t *testing.T) {
+	r := newTestResolver()
+	audit := &auditStorage{Storage: r.Storage}
+	r.Storage = audit
+	words, _ := filter.NewBannedWords([]string{"spam"}, domain.FilterRewrite)
+	links, _ := filter.NewLinks(0, domain.FilterReject)
+	r.Filters = &filter.Pipeline{Filters: []filter.Filter{words, links}}
+	alice := asUser("alice")
+
+	p, err := r.Mutation().CreatePost(alice, model.CreatePostInput{Title: "t", Content: "c", CommentsAllowed: false})
+	if err != nil {
+		t.Fatalf("CreatePost error: %v", err)
+	}
+	if _, err := r.Mutation().CreateComment(alice, model.CreateCommentInput{PostID: p.ID, Content: "spam"}); !errors.Is(err, domain.ErrCommentsDisabled) {
+		t.Fatalf("expected ErrCommentsDisabled, got %v", err)
+	}
+	if len(audit.decisions) != 0 {
+		t.Fatalf("expected no decisions for a comment that was not stored, got %+v", audit.decisions)
+	}
+
+	_, err = r.Mutation().CreateComment(alice, model.CreateCommentInput{PostID: p.ID, Content: "spam at www.example.com"})
+	if domain.KindOf(err) != domain.KindValidation {
+		t.Fatalf("expected a rejection, got %v", err)
+	}
+	if len(audit.decisions) != 1 || audit.decisions[0].Action != domain.FilterReject {
+		t.Fatalf("expected only the rejection to be recorded, got %+v", audit.decisions)
+	}
+
+	if _, err := r.Mutation().SetCommentsAllowed(alice, p.ID, true); err != nil {
+		t.Fatalf("SetCommentsAllowed error: %v", err)
+	}
+	c, err := r.Mutation().CreateComment(alice, model.CreateCommentInput{PostID: p.ID, Content: "spam"})
+	if err != nil {
+		t.Fatalf("CreateComment error: %v", err)
+	}
+	if last := audit.decisions[len(audit.decisions)-1]; len(audit.decisions) != 2 || last.CommentID != c.ID || last.Action != domain.FilterRewrite {
+		t.Fatalf("expected the rewrite of the stored comment to be recorded, got %+v", audit.decisions)
+	}
+}
+
+// runSubscription runs sub as a subscription operation through ext, the way
+// the executor does, and returns every response sent to the client.
+func runSubscription(ctx context.Context, ext *SubscriptionStatus, sub *pubsub.Subscription) []*graphql.Response {
+	ctx = graphql.WithOperationContext(ctx, &graphql.OperationContext{
+		Operation: &ast.OperationDefinition{Operation: ast.Subscription},
+	})
+	responses := ext.InterceptOperation(ctx, func(ctx context.Context) graphql.ResponseHandler {
+		ch := forward(ctx, sub, eventID, nil)
+		return func(context.Context) *graphql.Response {
+			return ext.InterceptResponse(ctx, func(context.Context) *graphql.Response {
+				if _, ok := <-ch; !ok {
+					return nil
+				}
+				return &graphql.Response{}
+			})
+		}
+	})
+
+	var out []*graphql.Response
+	for resp := responses(ctx); resp != nil; resp = responses(ctx) {
+		out = append(out, resp)
+	}
+	return out
+}
+
+func TestSubscriptionStatus_SlowConsumer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ps := pubsub.NewMemory(pubsub.Options{BufferSize: 1, Overflow: pubsub.OverflowDisconnect})
+	postID := uuid.New()
+	sub, err := ps.Subscribe(ctx, pubsub.Topic{Kind: pubsub.CommentDeleted, PostID: postID})
+	if err != nil {
+		t.Fatalf("Subscribe error: %v", err)
+	}
+	for range 4 {
+		_ = ps.Publish(ctx, pubsub.Event{Kind: pubsub.CommentDeleted, PostID: postID, ID: uuid.New()})
+	}
+
+	responses := runSubscription(ctx, &SubscriptionStatus{}, sub)
+	last := responses[len(responses)-1]
+	if len(last.Errors) != 1 || last.Errors[0].Extensions["code"] != CodeSlowConsumer {
+		t.Fatalf("expected a %s error, got %+v", CodeSlowConsumer, last)
+	}
+}
+
+func TestSubscriptionStatus_Drain(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	ps := pubsub.NewMemory(pubsub.Options{})
+	sub, err := ps.Subscribe(ctx, pubsub.Topic{Kind: pubsub.PostDeleted})
+	if err != nil {
+		t.Fatalf("Subscribe error: %v", err)
+	}
+
+	ext := &SubscriptionStatus{}
+	done := make(chan []*graphql.Response)
+	go func() { done <- runSubscription(ctx, ext, sub) }()
+
+	// Wait for the subscription to start before shutting down.
+	for {
+		ext.mu.Lock()
+		active := ext.active
+		ext.mu.Unlock()
+		if active == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := ps.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+	if err := ext.Drain(ctx); err != nil {
+		t.Fatalf("Drain error: %v", err)
+	}
+
+	responses := <-done
+	if len(responses) != 1 || responses[0].Errors[0].Extensions["code"] != CodeShuttingDown {
+		t.Fatalf("expected a %s error, got %+v", CodeShuttingDown, responses)
+	}
+}
+
+func TestCommentAdded_ReplaySince(t *testing.T) {
+	r := newTestResolver()
+	alice := asUser("alice")
+
+	p, err := r.Mutation().CreatePost(alice, model.CreatePostInput{Title: "t", Content: "c", CommentsAllowed: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := time.Now().Add(-time.Hour)
+	var stored []domain.Comment
+	for i := range 3 {
+		c := domain.Comment{ID: uuid.New(), PostID: p.ID, Content: "old", CreatedAt: base.Add(time.Duration(i) * time.Second)}
+		if err := r.Storage.CreateComment(context.Background(), c); err != nil {
+			t.Fatalf("CreateComment error: %v", err)
+		}
+		stored = append(stored, c)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	since := storage.CommentCursor(stored[0]).Encode()
+	added, err := r.Subscription().CommentAdded(ctx, p.ID, &since)
+	if err != nil {
+		t.Fatalf("CommentAdded error: %v", err)
+	}
+
+	// An event for an already replayed comment must not be delivered twice.
+	r.publish(context.Background(), pubsub.Event{Kind: pubsub.CommentAdded, PostID: p.ID, ID: stored[2].ID, Comment: &stored[2]})
+	live, err := r.Mutation().CreateComment(alice, model.CreateCommentInput{PostID: p.ID, Content: "new"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []uuid.UUID{stored[1].ID, stored[2].ID, live.ID}
+	for i, id := range want {
+		select {
+		case c := <-added:
+			if c.ID != id {
+				t.Fatalf("comment %d: expected %v, got %v", i, id, c.ID)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("comment %d: no event", i)
+		}
+	}
+
+	bad := "nope"
+	if _, err := r.Subscription().CommentAdded(ctx, p.ID, &bad); !errors.Is(err, storage.ErrInvalidCursor) {
+		t.Fatalf("expected ErrInvalidCursor, got %v", err)
+	}
+}
+
+func TestCommentAdded_ReplayOverflow(t *testing.T) {
+	for _, overflow := range []pubsub.Overflow{pubsub.OverflowCoalesce, pubsub.OverflowDisconnect} {
+		t.Run(string(overflow), func(t *testing.T) {
+			r := &Resolver{Storage: memory.New(), PubSub: pubsub.NewMemory(pubsub.Options{BufferSize: 2, Overflow: overflow})}
+			alice := asUser("alice")
+
+			p, err := r.Mutation().CreatePost(alice, model.CreatePostInput{Title: "t", Content: "c", CommentsAllowed: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			old := domain.Comment{ID: uuid.New(), PostID: p.ID, Content: "old", CreatedAt: time.Now().Add(-time.Hour)}
+			if err := r.Storage.CreateComment(context.Background(), old); err != nil {
+				t.Fatalf("CreateComment error: %v", err)
+			}
+
+			st := &subscriptionState{}
+			ctx, cancel := context.WithCancel(context.WithValue(context.Background(), subscriptionStateKey{}, st))
+			defer cancel()
+			added, err := r.Subscription().CommentAdded(ctx, p.ID, strPtr(storage.Cursor{}.Encode()))
+			if err != nil {
+				t.Fatalf("CommentAdded error: %v", err)
+			}
+
+			// The replay is stalled on the old comment, which nobody reads,
+			// while more live comments arrive than the buffer holds.
+			var ids []uuid.UUID
+			for range 5 {
+				c, err := r.Mutation().CreateComment(alice, model.CreateCommentInput{PostID: p.ID, Content: "new"})
+				if err != nil {
+					t.Fatal(err)
+				}
+				ids = append(ids, c.ID)
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			var got []uuid.UUID
+			for c := range added {
+				got = append(got, c.ID)
+				if len(got) == 3 {
+					break
+				}
+			}
+
+			st.mu.Lock()
+			defer st.mu.Unlock()
+			if overflow == pubsub.OverflowDisconnect {
+				if len(got) != 0 || !errors.Is(st.err, pubsub.ErrSlowConsumer) {
+					t.Fatalf("expected the subscription to end with ErrSlowConsumer, got %v and %v", got, st.err)
+				}
+				return
+			}
+			want := []uuid.UUID{old.ID, ids[3], ids[4]}
+			if !slices.Equal(got, want) {
+				t.Fatalf("expected %v, got %v", want, got)
+			}
+			if st.missed != 3 {
+				t.Fatalf("expected 3 missed events, got %d", st.missed)
+			}
+		})
+	}
+}
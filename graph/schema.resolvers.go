@@ -7,76 +7,420 @@ package graph
 import (
 	"context"
 	"posts-comments-1/graph/model"
+	"posts-comments-1/graph/pubsub"
 	"posts-comments-1/internal/domain"
+	"posts-comments-1/internal/storage"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// Post is the resolver for the post field.
+func (r *commentResolver) Post(ctx context.Context, obj *domain.Comment) (*domain.Post, error) {
+	return r.loaders(ctx).posts.Load(ctx, obj.PostID)
+}
+
+// Parent is the resolver for the parent field.
+func (r *commentResolver) Parent(ctx context.Context, obj *domain.Comment) (*domain.Comment, error) {
+	if obj.ParentID == nil {
+		return nil, nil
+	}
+	return r.loaders(ctx).comments.Load(ctx, *obj.ParentID)
+}
+
+// Content is the resolver for the content field.
+func (r *commentResolver) Content(ctx context.Context, obj *domain.Comment) (string, error) {
+	switch {
+	case obj.Deleted():
+		return domain.TombstoneContent, nil
+	case isModerator(ctx):
+		return obj.Content, nil
+	case obj.Status == domain.StatusRemoved:
+		return domain.RemovedContent, nil
+	case obj.Status == domain.StatusHidden:
+		return domain.HiddenContent, nil
+	}
+	return obj.Content, nil
+}
+
 // CreatedAt is the resolver for the createdAt field.
 func (r *commentResolver) CreatedAt(ctx context.Context, obj *domain.Comment) (string, error) {
 	return obj.CreatedAt.Format(time.RFC3339), nil
 }
 
+// Author is the resolver for the author field.
+func (r *commentResolver) Author(ctx context.Context, obj *domain.Comment) (*domain.User, error) {
+	return r.user(ctx, obj.AuthorID)
+}
+
+// EditedAt is the resolver for the editedAt field.
+func (r *commentResolver) EditedAt(ctx context.Context, obj *domain.Comment) (*string, error) {
+	if obj.EditedAt == nil {
+		return nil, nil
+	}
+	s := obj.EditedAt.Format(time.RFC3339)
+	return &s, nil
+}
+
+// RevisionCount is the resolver for the revisionCount field.
+func (r *commentResolver) RevisionCount(ctx context.Context, obj *domain.Comment) (int32, error) {
+	return int32(obj.RevisionCount), nil
+}
+
+// Revisions is the resolver for the revisions field.
+func (r *commentResolver) Revisions(ctx context.Context, obj *domain.Comment, first *int32, after *string) (*model.CommentRevisionConnection, error) {
+	if err := requireAuthorOrModerator(ctx, obj.AuthorID); err != nil {
+		return nil, err
+	}
+	p, err := pageParams(first, after, nil, nil, defaultRevisionsPage)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := r.Storage.GetCommentRevisions(ctx, obj.ID, p)
+	if err != nil {
+		return nil, err
+	}
+	return revisionConnection(page), nil
+}
+
+// ReplyCount is the resolver for the replyCount field.
+func (r *commentResolver) ReplyCount(ctx context.Context, obj *domain.Comment) (int32, error) {
+	n, err := r.loaders(ctx).replyCounts.Load(ctx, obj.ID)
+	if err != nil {
+		return 0, err
+	}
+	return int32(n), nil
+}
+
+// Replies is the resolver for the replies field.
+func (r *commentResolver) Replies(ctx context.Context, obj *domain.Comment, first *int32, after *string) (*model.CommentConnection, error) {
+	p, err := pageParams(first, after, nil, nil, defaultRepliesPage)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := r.Storage.GetReplies(ctx, obj.ID, p, isModerator(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return commentConnection(page), nil
+}
+
+// DeletedAt is the resolver for the deletedAt field.
+func (r *commentResolver) DeletedAt(ctx context.Context, obj *domain.Comment) (*string, error) {
+	if obj.DeletedAt == nil {
+		return nil, nil
+	}
+	s := obj.DeletedAt.Format(time.RFC3339)
+	return &s, nil
+}
+
+// DeletedBy is the resolver for the deletedBy field.
+func (r *commentResolver) DeletedBy(ctx context.Context, obj *domain.Comment) (*domain.User, error) {
+	if !isModerator(ctx) {
+		return nil, nil
+	}
+	return r.user(ctx, obj.DeletedBy)
+}
+
+// Cursor is the resolver for the cursor field.
+func (r *commentResolver) Cursor(ctx context.Context, obj *domain.Comment) (string, error) {
+	return storage.CommentCursor(*obj).Encode(), nil
+}
+
+// Comment is the resolver for the comment field.
+func (r *commentFlagResolver) Comment(ctx context.Context, obj *domain.CommentFlag) (*domain.Comment, error) {
+	return r.loaders(ctx).comments.Load(ctx, obj.CommentID)
+}
+
+// Reporter is the resolver for the reporter field.
+func (r *commentFlagResolver) Reporter(ctx context.Context, obj *domain.CommentFlag) (*domain.User, error) {
+	return r.user(ctx, obj.ReporterID)
+}
+
+// CreatedAt is the resolver for the createdAt field.
+func (r *commentFlagResolver) CreatedAt(ctx context.Context, obj *domain.CommentFlag) (string, error) {
+	return obj.CreatedAt.Format(time.RFC3339), nil
+}
+
+// ResolvedAt is the resolver for the resolvedAt field.
+func (r *commentFlagResolver) ResolvedAt(ctx context.Context, obj *domain.CommentFlag) (*string, error) {
+	if obj.ResolvedAt == nil {
+		return nil, nil
+	}
+	s := obj.ResolvedAt.Format(time.RFC3339)
+	return &s, nil
+}
+
+// ResolvedBy is the resolver for the resolvedBy field.
+func (r *commentFlagResolver) ResolvedBy(ctx context.Context, obj *domain.CommentFlag) (*domain.User, error) {
+	return r.user(ctx, obj.ResolvedBy)
+}
+
+// Resolution is the resolver for the resolution field.
+func (r *commentFlagResolver) Resolution(ctx context.Context, obj *domain.CommentFlag) (*domain.ModerationStatus, error) {
+	if !obj.Resolved() {
+		return nil, nil
+	}
+	return &obj.Resolution, nil
+}
+
+// Editor is the resolver for the editor field.
+func (r *commentRevisionResolver) Editor(ctx context.Context, obj *domain.CommentRevision) (*domain.User, error) {
+	return r.user(ctx, obj.EditorID)
+}
+
+// EditedAt is the resolver for the editedAt field.
+func (r *commentRevisionResolver) EditedAt(ctx context.Context, obj *domain.CommentRevision) (string, error) {
+	return obj.CreatedAt.Format(time.RFC3339), nil
+}
+
 // CreatePost is the resolver for the createPost field.
 func (r *mutationResolver) CreatePost(ctx context.Context, input model.CreatePostInput) (*domain.Post, error) {
+	authorID, err := r.callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	p := domain.Post{
 		ID:              uuid.New(),
+		AuthorID:        authorID,
 		Title:           input.Title,
 		Content:         input.Content,
 		CommentsAllowed: input.CommentsAllowed,
-		CreatedAt:       time.Now(),
+		CreatedAt:       now(),
+	}
+	if err := domain.ValidatePost(p); err != nil {
+		return nil, err
 	}
 
-	if err := r.Storage.CreatePost(p); err != nil {
+	if err := r.Storage.CreatePost(ctx, p); err != nil {
 		return nil, err
 	}
 	return &p, nil
 }
 
+// UpdatePost is the resolver for the updatePost field.
+func (r *mutationResolver) UpdatePost(ctx context.Context, input model.UpdatePostInput) (*domain.Post, error) {
+	p, err := r.Storage.GetPost(ctx, input.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireAuthor(ctx, p.AuthorID); err != nil {
+		return nil, err
+	}
+
+	if input.Title != nil {
+		p.Title = *input.Title
+	}
+	if input.Content != nil {
+		p.Content = *input.Content
+	}
+	if input.CommentsAllowed != nil {
+		p.CommentsAllowed = *input.CommentsAllowed
+	}
+	if err := domain.ValidatePost(*p); err != nil {
+		return nil, err
+	}
+
+	if err := r.Storage.UpdatePost(ctx, *p); err != nil {
+		return nil, err
+	}
+
+	r.publish(ctx, pubsub.Event{Kind: pubsub.PostUpdated, PostID: p.ID, ID: p.ID, Post: p})
+	return p, nil
+}
+
+// DeletePost is the resolver for the deletePost field.
+func (r *mutationResolver) DeletePost(ctx context.Context, id uuid.UUID) (uuid.UUID, error) {
+	p, err := r.Storage.GetPost(ctx, id)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if err := requireAuthor(ctx, p.AuthorID); err != nil {
+		return uuid.Nil, err
+	}
+
+	if err := r.Storage.DeletePost(ctx, id); err != nil {
+		return uuid.Nil, err
+	}
+
+	r.publish(ctx, pubsub.Event{Kind: pubsub.PostDeleted, PostID: id, ID: id})
+	return id, nil
+}
+
 // SetCommentsAllowed is the resolver for the setCommentsAllowed field.
 func (r *mutationResolver) SetCommentsAllowed(ctx context.Context, postID uuid.UUID, allowed bool) (*domain.Post, error) {
-	p, err := r.Storage.GetPost(postID)
+	p, err := r.Storage.GetPost(ctx, postID)
 	if err != nil {
 		return nil, err
 	}
+	if err := requireAuthor(ctx, p.AuthorID); err != nil {
+		return nil, err
+	}
 
 	p.CommentsAllowed = allowed
-	if err := r.Storage.UpdatePost(*p); err != nil {
+	if err := r.Storage.UpdatePost(ctx, *p); err != nil {
 		return nil, err
 	}
 
+	r.publish(ctx, pubsub.Event{Kind: pubsub.PostUpdated, PostID: p.ID, ID: p.ID, Post: p})
 	return p, nil
 }
 
 // CreateComment is the resolver for the createComment field.
 func (r *mutationResolver) CreateComment(ctx context.Context, input model.CreateCommentInput) (*domain.Comment, error) {
+	authorID, err := r.callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	c := domain.Comment{
 		ID:        uuid.New(),
 		PostID:    input.PostID,
+		AuthorID:  authorID,
 		ParentID:  input.ParentID,
 		Content:   input.Content,
-		CreatedAt: time.Now(),
+		CreatedAt: now(),
+		Status:    domain.StatusVisible,
+	}
+	if err := domain.ValidateCommentContent(c.Content); err != nil {
+		return nil, err
+	}
+	decisions, err := r.filterComment(ctx, &c, false)
+	if err != nil {
+		return nil, err
 	}
 
-	if err := r.Storage.CreateComment(c); err != nil {
+	if err := r.Storage.CreateComment(ctx, c); err != nil {
 		return nil, err
 	}
-	r.publishComment(&c)
+	r.applyFilterDecisions(ctx, &c, decisions)
+	r.publish(ctx, pubsub.Event{Kind: pubsub.CommentAdded, PostID: c.PostID, ID: c.ID, Comment: &c})
 	return &c, nil
 }
 
-func (r *Resolver) publishComment(c *domain.Comment) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+// UpdateComment is the resolver for the updateComment field.
+func (r *mutationResolver) UpdateComment(ctx context.Context, input model.UpdateCommentInput) (*domain.Comment, error) {
+	c, err := r.Storage.GetComment(ctx, input.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireAuthor(ctx, c.AuthorID); err != nil {
+		return nil, err
+	}
+	if err := domain.ValidateCommentContent(input.Content); err != nil {
+		return nil, err
+	}
 
-	subs := r.subscribers[c.PostID]
-	for _, ch := range subs {
-		select {
-		case ch <- c:
-		default:
-		}
+	editorID, err := r.callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	editedAt := now()
+	c.Content = input.Content
+	c.EditedAt = &editedAt
+	decisions, err := r.filterComment(ctx, c, true)
+	if err != nil {
+		return nil, err
+	}
+	c, err = r.Storage.UpdateComment(ctx, *c, editorID)
+	if err != nil {
+		return nil, err
+	}
+	r.applyFilterDecisions(ctx, c, decisions)
+
+	r.publish(ctx, pubsub.Event{Kind: pubsub.CommentUpdated, PostID: c.PostID, ID: c.ID, Comment: c})
+	return c, nil
+}
+
+// DeleteComment is the resolver for the deleteComment field.
+func (r *mutationResolver) DeleteComment(ctx context.Context, id uuid.UUID) (uuid.UUID, error) {
+	c, err := r.Storage.GetComment(ctx, id)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if err := requireAuthorOrModerator(ctx, c.AuthorID); err != nil {
+		return uuid.Nil, err
+	}
+	deletedBy, err := r.callerID(ctx)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	if err := r.Storage.DeleteComment(ctx, id, deletedBy); err != nil {
+		return uuid.Nil, err
+	}
+
+	r.publish(ctx, pubsub.Event{Kind: pubsub.CommentDeleted, PostID: c.PostID, ID: id})
+	return id, nil
+}
+
+// PurgeComment is the resolver for the purgeComment field.
+func (r *mutationResolver) PurgeComment(ctx context.Context, id uuid.UUID) ([]uuid.UUID, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	c, err := r.Storage.GetComment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	ids, err := r.Storage.PurgeComment(ctx, id)
+	if err != nil {
+		return nil, err
 	}
+
+	for _, id := range ids {
+		r.publish(ctx, pubsub.Event{Kind: pubsub.CommentDeleted, PostID: c.PostID, ID: id})
+	}
+	return ids, nil
+}
+
+// FlagComment is the resolver for the flagComment field.
+func (r *mutationResolver) FlagComment(ctx context.Context, commentID uuid.UUID, reason string) (*domain.CommentFlag, error) {
+	if err := domain.ValidateFlagReason(reason); err != nil {
+		return nil, err
+	}
+	reporterID, err := r.callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	f := domain.CommentFlag{
+		ID:         uuid.New(),
+		CommentID:  commentID,
+		ReporterID: reporterID,
+		Reason:     reason,
+		CreatedAt:  now(),
+	}
+	if err := r.Storage.FlagComment(ctx, f); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// ResolveFlag is the resolver for the resolveFlag field.
+func (r *mutationResolver) ResolveFlag(ctx context.Context, flagID uuid.UUID, status domain.ModerationStatus) (*domain.Comment, error) {
+	if err := requireModerator(ctx); err != nil {
+		return nil, err
+	}
+	if !domain.ValidResolution(status) {
+		return nil, domain.ErrInvalidResolution
+	}
+	resolvedBy, err := r.callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := r.Storage.ResolveFlag(ctx, flagID, resolvedBy, status)
+	if err != nil {
+		return nil, err
+	}
+
+	r.publish(ctx, pubsub.Event{Kind: pubsub.CommentUpdated, PostID: c.PostID, ID: c.ID, Comment: c})
+	return c, nil
 }
 
 // CreatePost is the resolver for the createPost field.
@@ -84,9 +428,40 @@ func (r *postResolver) CreatedAt(ctx context.Context, obj *domain.Post) (string,
 	return obj.CreatedAt.Format(time.RFC3339), nil
 }
 
+// Author is the resolver for the author field.
+func (r *postResolver) Author(ctx context.Context, obj *domain.Post) (*domain.User, error) {
+	return r.user(ctx, obj.AuthorID)
+}
+
+// CommentCount is the resolver for the commentCount field.
+func (r *postResolver) CommentCount(ctx context.Context, obj *domain.Post) (int32, error) {
+	n, err := r.loaders(ctx).commentCounts.Load(ctx, obj.ID)
+	if err != nil {
+		return 0, err
+	}
+	return int32(n), nil
+}
+
+// CommentTree is the resolver for the commentTree field.
+func (r *postResolver) CommentTree(ctx context.Context, obj *domain.Post, maxDepth int32, repliesPerNode int32) ([]*model.CommentTreeNode, error) {
+	if maxDepth < 1 || maxDepth > maxTreeDepth || repliesPerNode < 1 || repliesPerNode > maxRepliesPerNode {
+		return nil, ErrInvalidTreeLimits
+	}
+
+	comments, err := r.Storage.GetCommentTree(ctx, obj.ID, int(maxDepth), int(repliesPerNode), isModerator(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return buildCommentTree(comments), nil
+}
+
 // Posts is the resolver for the posts field.
 func (r *queryResolver) Posts(ctx context.Context, limit int32, offset int32) ([]*domain.Post, error) {
-	posts, err := r.Storage.ListPosts(int(limit), int(offset))
+	n, err := legacyLimit(limit)
+	if err != nil {
+		return nil, err
+	}
+	posts, err := r.Storage.ListPosts(ctx, n, int(offset))
 	if err != nil {
 		return nil, err
 	}
@@ -99,14 +474,32 @@ func (r *queryResolver) Posts(ctx context.Context, limit int32, offset int32) ([
 	return out, nil
 }
 
+// PostsConnection is the resolver for the postsConnection field.
+func (r *queryResolver) PostsConnection(ctx context.Context, first *int32, after *string, last *int32, before *string) (*model.PostConnection, error) {
+	p, err := pageParams(first, after, last, before, defaultPostsPage)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := r.Storage.ListPostsPage(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	return postConnection(page), nil
+}
+
 // Post is the resolver for the post field.
 func (r *queryResolver) Post(ctx context.Context, id uuid.UUID) (*domain.Post, error) {
-	return r.Storage.GetPost(id)
+	return r.Storage.GetPost(ctx, id)
 }
 
 // Comments is the resolver for the comments field.
 func (r *queryResolver) Comments(ctx context.Context, postID uuid.UUID, limit int32, offset int32) ([]*domain.Comment, error) {
-	comments, err := r.Storage.GetComments(postID, int(limit), int(offset))
+	n, err := legacyLimit(limit)
+	if err != nil {
+		return nil, err
+	}
+	comments, err := r.Storage.GetComments(ctx, postID, n, int(offset), isModerator(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -119,45 +512,107 @@ func (r *queryResolver) Comments(ctx context.Context, postID uuid.UUID, limit in
 	return out, nil
 }
 
+// CommentsConnection is the resolver for the commentsConnection field.
+func (r *queryResolver) CommentsConnection(ctx context.Context, postID uuid.UUID, first *int32, after *string, last *int32, before *string) (*model.CommentConnection, error) {
+	p, err := pageParams(first, after, last, before, defaultCommentsPage)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := r.Storage.GetCommentsPage(ctx, postID, p, isModerator(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return commentConnection(page), nil
+}
+
+// ModerationQueue is the resolver for the moderationQueue field.
+func (r *queryResolver) ModerationQueue(ctx context.Context, first *int32, after *string) (*model.CommentFlagConnection, error) {
+	if err := requireModerator(ctx); err != nil {
+		return nil, err
+	}
+	p, err := pageParams(first, after, nil, nil, defaultFlagsPage)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := r.Storage.GetOpenFlags(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	return flagConnection(page), nil
+}
+
 // CommentAdded is the resolver for the commentAdded field.
-func (r *subscriptionResolver) CommentAdded(ctx context.Context, postID uuid.UUID) (<-chan *domain.Comment, error) {
-	ch := make(chan *domain.Comment, 16)
-
-	r.mu.Lock()
-	r.nextSubID++
-	id := r.nextSubID
-
-	if r.subscribers == nil {
-		r.subscribers = make(map[uuid.UUID]map[int]chan *domain.Comment)
-	}
-	if r.subscribers[postID] == nil {
-		r.subscribers[postID] = make(map[int]chan *domain.Comment)
-	}
-	r.subscribers[postID][id] = ch
-	r.mu.Unlock()
-
-	go func() {
-		<-ctx.Done()
-
-		r.mu.Lock()
-		if m := r.subscribers[postID]; m != nil {
-			if subCh, ok := m[id]; ok {
-				close(subCh)
-				delete(m, id)
-			}
-			if len(m) == 0 {
-				delete(r.subscribers, postID)
-			}
+func (r *subscriptionResolver) CommentAdded(ctx context.Context, postID uuid.UUID, since *string) (<-chan *domain.Comment, error) {
+	var from storage.Cursor
+	if since != nil {
+		c, err := storage.DecodeCursor(*since)
+		if err != nil {
+			return nil, err
 		}
-		r.mu.Unlock()
-	}()
+		from = c
+	}
+
+	sub, err := r.PubSub.Subscribe(ctx, pubsub.Topic{Kind: pubsub.CommentAdded, PostID: postID})
+	if err != nil {
+		return nil, err
+	}
+	live := forward(ctx, sub, eventComment, r.loadComment)
+	if !isModerator(ctx) {
+		live = withoutHidden(ctx, live)
+	}
+	if since == nil {
+		return live, nil
+	}
+	return r.replayComments(ctx, postID, from, live)
+}
 
-	return ch, nil
+// CommentUpdated is the resolver for the commentUpdated field.
+func (r *subscriptionResolver) CommentUpdated(ctx context.Context, postID uuid.UUID) (<-chan *domain.Comment, error) {
+	sub, err := r.PubSub.Subscribe(ctx, pubsub.Topic{Kind: pubsub.CommentUpdated, PostID: postID})
+	if err != nil {
+		return nil, err
+	}
+	return forward(ctx, sub, eventComment, r.loadComment), nil
+}
+
+// CommentDeleted is the resolver for the commentDeleted field.
+func (r *subscriptionResolver) CommentDeleted(ctx context.Context, postID uuid.UUID) (<-chan uuid.UUID, error) {
+	sub, err := r.PubSub.Subscribe(ctx, pubsub.Topic{Kind: pubsub.CommentDeleted, PostID: postID})
+	if err != nil {
+		return nil, err
+	}
+	return forward(ctx, sub, eventID, nil), nil
+}
+
+// PostUpdated is the resolver for the postUpdated field.
+func (r *subscriptionResolver) PostUpdated(ctx context.Context, postID *uuid.UUID) (<-chan *domain.Post, error) {
+	sub, err := r.PubSub.Subscribe(ctx, pubsub.Topic{Kind: pubsub.PostUpdated, PostID: optionalID(postID)})
+	if err != nil {
+		return nil, err
+	}
+	return forward(ctx, sub, eventPost, r.loadPost), nil
+}
+
+// PostDeleted is the resolver for the postDeleted field.
+func (r *subscriptionResolver) PostDeleted(ctx context.Context, postID *uuid.UUID) (<-chan uuid.UUID, error) {
+	sub, err := r.PubSub.Subscribe(ctx, pubsub.Topic{Kind: pubsub.PostDeleted, PostID: optionalID(postID)})
+	if err != nil {
+		return nil, err
+	}
+	return forward(ctx, sub, eventID, nil), nil
 }
 
 // Comment returns CommentResolver implementation.
 func (r *Resolver) Comment() CommentResolver { return &commentResolver{r} }
 
+// CommentFlag returns CommentFlagResolver implementation.
+func (r *Resolver) CommentFlag() CommentFlagResolver { return &commentFlagResolver{r} }
+
+// CommentRevision returns CommentRevisionResolver implementation.
+func (r *Resolver) CommentRevision() CommentRevisionResolver { return &commentRevisionResolver{r} }
+
 // Mutation returns MutationResolver implementation.
 func (r *Resolver) Mutation() MutationResolver { return &mutationResolver{r} }
 
@@ -171,6 +626,8 @@ func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
 func (r *Resolver) Subscription() SubscriptionResolver { return &subscriptionResolver{r} }
 
 type commentResolver struct{ *Resolver }
+type commentFlagResolver struct{ *Resolver }
+type commentRevisionResolver struct{ *Resolver }
 type mutationResolver struct{ *Resolver }
 type postResolver struct{ *Resolver }
 type queryResolver struct{ *Resolver }
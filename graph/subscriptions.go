@@ -0,0 +1,176 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+
+	"posts-comments-1/graph/pubsub"
+)
+
+// Codes of the final error sent when the server ends a subscription.
+const (
+	// CodeSlowConsumer: the subscriber was disconnected for falling behind.
+	CodeSlowConsumer = "SLOW_CONSUMER"
+	// CodeShuttingDown: the server is stopping, clients should reconnect.
+	CodeShuttingDown = "SHUTTING_DOWN"
+)
+
+// SubscriptionStatus tells subscription clients what the pub/sub did to
+// their stream: responses that follow dropped events carry
+// extensions.missedEvents, and a subscription ended by the server for being
+// too slow or for a shutdown ends with an error carrying one of the codes
+// above instead of silently completing.
+//
+// It also counts running subscriptions, so a shutdown can wait until their
+// final errors are sent before closing the connections.
+type SubscriptionStatus struct {
+	mu     sync.Mutex
+	active int
+	// idle is closed whenever active drops to zero.
+	idle chan struct{}
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationInterceptor
+	graphql.ResponseInterceptor
+} = &SubscriptionStatus{}
+
+func (*SubscriptionStatus) ExtensionName() string {
+	return "SubscriptionStatus"
+}
+
+func (*SubscriptionStatus) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+func (s *SubscriptionStatus) InterceptOperation(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+	oc := graphql.GetOperationContext(ctx)
+	if oc.Operation == nil || oc.Operation.Operation != ast.Subscription {
+		return next(ctx)
+	}
+
+	s.mu.Lock()
+	if s.active == 0 {
+		s.idle = make(chan struct{})
+	}
+	s.active++
+	s.mu.Unlock()
+
+	return next(context.WithValue(ctx, subscriptionStateKey{}, &subscriptionState{}))
+}
+
+func (s *SubscriptionStatus) InterceptResponse(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	resp := next(ctx)
+
+	st, _ := ctx.Value(subscriptionStateKey{}).(*subscriptionState)
+	if st == nil {
+		return resp
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if resp == nil {
+		if st.reported {
+			s.finish(st)
+			return nil
+		}
+		var code, msg string
+		switch {
+		case errors.Is(st.err, pubsub.ErrSlowConsumer):
+			code, msg = CodeSlowConsumer, st.err.Error()
+		case errors.Is(st.err, pubsub.ErrClosed):
+			code, msg = CodeShuttingDown, "server is shutting down"
+		default:
+			s.finish(st)
+			return nil
+		}
+		st.reported = true
+		return &graphql.Response{Errors: gqlerror.List{{
+			Message:    msg,
+			Extensions: map[string]any{"code": code},
+		}}}
+	}
+
+	if st.missed > 0 {
+		if resp.Extensions == nil {
+			resp.Extensions = map[string]any{}
+		}
+		resp.Extensions["missedEvents"] = st.missed
+		st.missed = 0
+	}
+	return resp
+}
+
+// Drain waits until every running subscription has ended or ctx is done.
+func (s *SubscriptionStatus) Drain(ctx context.Context) error {
+	s.mu.Lock()
+	if s.active == 0 {
+		s.mu.Unlock()
+		return nil
+	}
+	idle := s.idle
+	s.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// finish is called with st.mu held once the last response of a
+// subscription was produced.
+func (s *SubscriptionStatus) finish(st *subscriptionState) {
+	if st.finished {
+		return
+	}
+	st.finished = true
+
+	s.mu.Lock()
+	s.active--
+	if s.active == 0 {
+		close(s.idle)
+	}
+	s.mu.Unlock()
+}
+
+type subscriptionStateKey struct{}
+
+// subscriptionState is shared between the resolver goroutine forwarding
+// events and the responses sent for them.
+type subscriptionState struct {
+	mu       sync.Mutex
+	missed   int
+	err      error
+	reported bool
+	finished bool
+}
+
+func (st *subscriptionState) addMissed(n int) {
+	if st == nil || n == 0 {
+		return
+	}
+	st.mu.Lock()
+	st.missed += n
+	st.mu.Unlock()
+}
+
+// end records why the subscription ended; the first reason is kept.
+func (st *subscriptionState) end(err error) {
+	if st == nil {
+		return
+	}
+	st.mu.Lock()
+	if st.err == nil {
+		st.err = err
+	}
+	st.mu.Unlock()
+}
@@ -0,0 +1,38 @@
+package graph
+
+import (
+	"github.com/google/uuid"
+
+	"posts-comments-1/graph/model"
+	"posts-comments-1/internal/domain"
+)
+
+const (
+	maxTreeDepth      = 10
+	maxRepliesPerNode = 100
+)
+
+var ErrInvalidTreeLimits = domain.NewError(domain.KindValidation, "maxDepth must be between 1 and 10, repliesPerNode between 1 and 100")
+
+// buildCommentTree nests the flat result of Storage.GetCommentTree, which
+// lists every parent before its replies.
+func buildCommentTree(comments []domain.Comment) []*model.CommentTreeNode {
+	nodes := make(map[uuid.UUID]*model.CommentTreeNode, len(comments))
+	roots := make([]*model.CommentTreeNode, 0)
+
+	for i := range comments {
+		c := comments[i]
+		node := &model.CommentTreeNode{Comment: &c, Depth: 1, Replies: []*model.CommentTreeNode{}}
+		nodes[c.ID] = node
+
+		if c.ParentID != nil {
+			if parent, ok := nodes[*c.ParentID]; ok {
+				node.Depth = parent.Depth + 1
+				parent.Replies = append(parent.Replies, node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+	return roots
+}
@@ -0,0 +1,32 @@
+package graph
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+
+	"posts-comments-1/internal/domain"
+)
+
+func TestBuildCommentTree(t *testing.T) {
+	root := domain.Comment{ID: uuid.New()}
+	child := domain.Comment{ID: uuid.New(), ParentID: &root.ID}
+	grandchild := domain.Comment{ID: uuid.New(), ParentID: &child.ID}
+	other := domain.Comment{ID: uuid.New()}
+
+	tree := buildCommentTree([]domain.Comment{root, other, child, grandchild})
+
+	if len(tree) != 2 {
+		t.Fatalf("expected 2 roots, got %d", len(tree))
+	}
+	if len(tree[0].Replies) != 1 || tree[0].Replies[0].Comment.ID != child.ID {
+		t.Fatalf("expected child under root, got %+v", tree[0].Replies)
+	}
+	leaf := tree[0].Replies[0].Replies[0]
+	if leaf.Comment.ID != grandchild.ID || leaf.Depth != 3 {
+		t.Fatalf("expected grandchild at depth 3, got %+v", leaf)
+	}
+	if len(tree[1].Replies) != 0 {
+		t.Fatalf("expected no replies under second root")
+	}
+}
@@ -0,0 +1,186 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+
+	"posts-comments-1/internal/domain"
+)
+
+func signHS256(t *testing.T, secret string, c jwt.MapClaims) string {
+	t.Helper()
+	s, err := jwt.NewWithClaims(jwt.SigningMethodHS256, c).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	return s
+}
+
+func validClaims(sub uuid.UUID) jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub":  sub.String(),
+		"name": "alice",
+		"exp":  time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func TestAuthenticate_HS256(t *testing.T) {
+	a, err := New(Config{HS256Secret: "secret"})
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+
+	id := uuid.New()
+	u, err := a.Authenticate(signHS256(t, "secret", validClaims(id)))
+	if err != nil {
+		t.Fatalf("Authenticate error: %v", err)
+	}
+	if u.ID != id || u.Name != "alice" {
+		t.Fatalf("unexpected user %+v", u)
+	}
+
+	if u.HasRole(domain.RoleModerator) {
+		t.Fatal("expected no roles without the roles claim")
+	}
+
+	admin := validClaims(id)
+	admin["roles"] = []string{"admin"}
+	u, err = a.Authenticate(signHS256(t, "secret", admin))
+	if err != nil {
+		t.Fatalf("Authenticate error: %v", err)
+	}
+	if !u.HasRole(domain.RoleModerator) || !u.HasRole(domain.RoleAdmin) {
+		t.Fatalf("expected an admin to have every role, got %v", u.Roles)
+	}
+
+	if _, err := a.Authenticate(signHS256(t, "other", validClaims(id))); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected ErrInvalidToken for wrong secret, got %v", err)
+	}
+
+	expired := validClaims(id)
+	expired["exp"] = time.Now().Add(-time.Minute).Unix()
+	if _, err := a.Authenticate(signHS256(t, "secret", expired)); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected ErrInvalidToken for expired token, got %v", err)
+	}
+
+	bad := validClaims(id)
+	bad["sub"] = "not-a-uuid"
+	if _, err := a.Authenticate(signHS256(t, "secret", bad)); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected ErrInvalidToken for non-UUID subject, got %v", err)
+	}
+}
+
+func TestAuthenticate_RS256FromJWKS(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwks := map[string]any{"keys": []map[string]any{{
+		"kty": "RSA",
+		"kid": "k1",
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}}}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	b, _ := json.Marshal(jwks)
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := New(Config{JWKSFile: path})
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+
+	id := uuid.New()
+	tok := jwt.NewWithClaims(jwt.SigningMethodRS256, validClaims(id))
+	tok.Header["kid"] = "k1"
+	signed, err := tok.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := a.Authenticate(signed)
+	if err != nil {
+		t.Fatalf("Authenticate error: %v", err)
+	}
+	if u.ID != id {
+		t.Fatalf("expected %v, got %v", id, u.ID)
+	}
+
+	// HS256 is not configured, so an HS256 token must be rejected.
+	if _, err := a.Authenticate(signHS256(t, "secret", validClaims(id))); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected ErrInvalidToken, got %v", err)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	a, err := New(Config{HS256Secret: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var gotUser bool
+	h := a.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, gotUser = UserFromContext(r.Context())
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/query", nil))
+	if rec.Code != http.StatusOK || gotUser {
+		t.Fatalf("expected anonymous pass-through, got %d user=%v", rec.Code, gotUser)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/query", nil)
+	req.Header.Set("Authorization", "Bearer "+signHS256(t, "secret", validClaims(uuid.New())))
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || !gotUser {
+		t.Fatalf("expected authenticated request, got %d user=%v", rec.Code, gotUser)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/query", nil)
+	req.Header.Set("Authorization", "Bearer garbage")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", rec.Code)
+	}
+}
+
+func TestWebsocketInit(t *testing.T) {
+	a, err := New(Config{HS256Secret: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id := uuid.New()
+	payload := transport.InitPayload{"Authorization": "Bearer " + signHS256(t, "secret", validClaims(id))}
+	ctx, _, err := a.WebsocketInit(t.Context(), payload)
+	if err != nil {
+		t.Fatalf("WebsocketInit error: %v", err)
+	}
+	if u, ok := UserFromContext(ctx); !ok || u.ID != id {
+		t.Fatalf("expected caller %v in context, got %+v", id, u)
+	}
+
+	if _, _, err := a.WebsocketInit(t.Context(), transport.InitPayload{"Authorization": "Bearer x"}); err == nil {
+		t.Fatalf("expected error for invalid token")
+	}
+}
@@ -0,0 +1,21 @@
+// Package auth carries the identity of the caller through request contexts.
+package auth
+
+import (
+	"context"
+
+	"posts-comments-1/internal/domain"
+)
+
+type ctxKey struct{}
+
+// WithUser returns a copy of ctx carrying the authenticated caller.
+func WithUser(ctx context.Context, u domain.User) context.Context {
+	return context.WithValue(ctx, ctxKey{}, u)
+}
+
+// UserFromContext returns the authenticated caller, if any.
+func UserFromContext(ctx context.Context) (domain.User, bool) {
+	u, ok := ctx.Value(ctxKey{}).(domain.User)
+	return u, ok
+}
@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+
+	"posts-comments-1/internal/domain"
+)
+
+var ErrInvalidToken = errors.New("invalid token")
+
+// Config selects the keys used to verify bearer tokens. HS256 tokens are
+// checked against HS256Secret, RS256 tokens against the key from the JWKS
+// file matching their "kid" header, or against the PEM key.
+type Config struct {
+	HS256Secret        string
+	RS256PublicKeyFile string
+	JWKSFile           string
+	Issuer             string
+	Audience           string
+}
+
+type Authenticator struct {
+	hmacSecret []byte
+	rsaKey     *rsa.PublicKey
+	jwks       map[string]*rsa.PublicKey
+	parser     *jwt.Parser
+}
+
+type claims struct {
+	jwt.RegisteredClaims
+	Name              string   `json:"name"`
+	PreferredUsername string   `json:"preferred_username"`
+	Roles             []string `json:"roles"`
+}
+
+func New(cfg Config) (*Authenticator, error) {
+	a := &Authenticator{hmacSecret: []byte(cfg.HS256Secret)}
+
+	if cfg.RS256PublicKeyFile != "" {
+		pem, err := os.ReadFile(cfg.RS256PublicKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("read rs256 public key: %w", err)
+		}
+		a.rsaKey, err = jwt.ParseRSAPublicKeyFromPEM(pem)
+		if err != nil {
+			return nil, fmt.Errorf("parse rs256 public key: %w", err)
+		}
+	}
+
+	if cfg.JWKSFile != "" {
+		keys, err := loadJWKS(cfg.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		a.jwks = keys
+	}
+
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"HS256", "RS256"}),
+		jwt.WithExpirationRequired(),
+	}
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.Audience))
+	}
+	a.parser = jwt.NewParser(opts...)
+
+	return a, nil
+}
+
+// Authenticate verifies a raw JWT and returns the user it identifies. The
+// "sub" claim must be a UUID; the display name comes from "name" or
+// "preferred_username", the roles from "roles".
+func (a *Authenticator) Authenticate(token string) (domain.User, error) {
+	var c claims
+	if _, err := a.parser.ParseWithClaims(token, &c, a.key); err != nil {
+		return domain.User{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	id, err := uuid.Parse(c.Subject)
+	if err != nil {
+		return domain.User{}, fmt.Errorf("%w: subject is not a UUID", ErrInvalidToken)
+	}
+
+	name := c.Name
+	if name == "" {
+		name = c.PreferredUsername
+	}
+	roles := make([]domain.Role, 0, len(c.Roles))
+	for _, r := range c.Roles {
+		roles = append(roles, domain.Role(r))
+	}
+	return domain.User{ID: id, Name: name, Roles: roles}, nil
+}
+
+func (a *Authenticator) key(t *jwt.Token) (any, error) {
+	switch t.Method.Alg() {
+	case "HS256":
+		if len(a.hmacSecret) == 0 {
+			return nil, errors.New("hs256 is not configured")
+		}
+		return a.hmacSecret, nil
+
+	case "RS256":
+		kid, _ := t.Header["kid"].(string)
+		if k, ok := a.jwks[kid]; ok && kid != "" {
+			return k, nil
+		}
+		if a.rsaKey != nil {
+			return a.rsaKey, nil
+		}
+		if kid == "" && len(a.jwks) == 1 {
+			for _, k := range a.jwks {
+				return k, nil
+			}
+		}
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return nil, fmt.Errorf("unexpected signing method %q", t.Method.Alg())
+}
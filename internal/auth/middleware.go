@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+
+	"posts-comments-1/internal/domain"
+)
+
+// Middleware authenticates requests carrying an "Authorization: Bearer"
+// header and puts the caller into the request context. Requests without the
+// header pass through anonymously; invalid tokens are rejected with 401.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		if header == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		u, err := a.authenticateHeader(header)
+		if err != nil {
+			writeUnauthorized(w)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithUser(r.Context(), u)))
+	})
+}
+
+// WebsocketInit is a transport.WebsocketInitFunc that authenticates the
+// "Authorization" value of the connection_init payload. Without it the
+// identity established by Middleware for the upgrade request is kept.
+func (a *Authenticator) WebsocketInit(ctx context.Context, payload transport.InitPayload) (context.Context, *transport.InitPayload, error) {
+	header := payload.Authorization()
+	if header == "" {
+		return ctx, nil, nil
+	}
+
+	u, err := a.authenticateHeader(header)
+	if err != nil {
+		return ctx, nil, err
+	}
+	return WithUser(ctx, u), nil, nil
+}
+
+func (a *Authenticator) authenticateHeader(header string) (domain.User, error) {
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return domain.User{}, ErrInvalidToken
+	}
+	return a.Authenticate(strings.TrimSpace(token))
+}
+
+func writeUnauthorized(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+	w.WriteHeader(http.StatusUnauthorized)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"errors": []map[string]any{{
+			"message":    ErrInvalidToken.Error(),
+			"extensions": map[string]any{"code": "UNAUTHENTICATED"},
+		}},
+	})
+}
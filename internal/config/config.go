@@ -1,14 +1,404 @@
-package main
+// Package config loads the server configuration from defaults, an optional
+// YAML or TOML file, environment variables and command-line flags, in that
+// order of precedence.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
 
 type Config struct {
-	StorageType string
-	Postgres    PostgresConfig
+	Server      ServerConfig    `yaml:"server" toml:"server"`
+	StorageType string          `yaml:"storage_type" toml:"storage_type"`
+	Postgres    PostgresConfig  `yaml:"postgres" toml:"postgres"`
+	PubSub      PubSubConfig    `yaml:"pubsub" toml:"pubsub"`
+	Auth        AuthConfig      `yaml:"auth" toml:"auth"`
+	Tracing     TracingConfig   `yaml:"tracing" toml:"tracing"`
+	Log         LogConfig       `yaml:"log" toml:"log"`
+	Limits      LimitsConfig    `yaml:"limits" toml:"limits"`
+	RateLimit   RateLimitConfig `yaml:"rate_limit" toml:"rate_limit"`
+	// PersistedQueries restricts the server to registered operations.
+	PersistedQueries PersistedQueriesConfig `yaml:"persisted_queries" toml:"persisted_queries"`
+	ContentFilters   ContentFiltersConfig   `yaml:"content_filters" toml:"content_filters"`
+	Features         FeaturesConfig         `yaml:"features" toml:"features"`
+}
+
+type ServerConfig struct {
+	ListenAddr         string        `yaml:"listen_addr" toml:"listen_addr"`
+	ReadHeaderTimeout  time.Duration `yaml:"read_header_timeout" toml:"read_header_timeout"`
+	WebsocketKeepAlive time.Duration `yaml:"websocket_keep_alive" toml:"websocket_keep_alive"`
+	// ShutdownTimeout bounds how long in-flight requests may run after a
+	// termination signal.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+	// HealthCheckTimeout bounds the checks behind /readyz.
+	HealthCheckTimeout time.Duration `yaml:"health_check_timeout" toml:"health_check_timeout"`
 }
 
 type PostgresConfig struct {
-	Host     string
-	Port     int
-	User     string
-	Password string
-	DBName   string
+	// DSN, when set, is used as is instead of the individual fields below.
+	DSN      string `yaml:"dsn" toml:"dsn"`
+	Host     string `yaml:"host" toml:"host"`
+	Port     int    `yaml:"port" toml:"port"`
+	User     string `yaml:"user" toml:"user"`
+	Password string `yaml:"password" toml:"password"`
+	DBName   string `yaml:"dbname" toml:"dbname"`
+
+	SSLMode     string `yaml:"sslmode" toml:"sslmode"`
+	SSLRootCert string `yaml:"sslrootcert" toml:"sslrootcert"`
+	SSLCert     string `yaml:"sslcert" toml:"sslcert"`
+	SSLKey      string `yaml:"sslkey" toml:"sslkey"`
+
+	MaxConns        int32         `yaml:"max_conns" toml:"max_conns"`
+	MinConns        int32         `yaml:"min_conns" toml:"min_conns"`
+	MaxConnLifetime time.Duration `yaml:"max_conn_lifetime" toml:"max_conn_lifetime"`
+	MaxConnIdleTime time.Duration `yaml:"max_conn_idle_time" toml:"max_conn_idle_time"`
+	ConnectTimeout  time.Duration `yaml:"connect_timeout" toml:"connect_timeout"`
+	QueryTimeout    time.Duration `yaml:"query_timeout" toml:"query_timeout"`
+}
+
+type PubSubConfig struct {
+	// Backend is "postgres" or "memory". Empty selects postgres with the
+	// postgres storage and memory otherwise.
+	Backend    string `yaml:"backend" toml:"backend"`
+	BufferSize int    `yaml:"buffer_size" toml:"buffer_size"`
+	Overflow   string `yaml:"overflow" toml:"overflow"`
+}
+
+type AuthConfig struct {
+	HS256Secret        string `yaml:"hs256_secret" toml:"hs256_secret"`
+	RS256PublicKeyFile string `yaml:"rs256_public_key_file" toml:"rs256_public_key_file"`
+	JWKSFile           string `yaml:"jwks_file" toml:"jwks_file"`
+	Issuer             string `yaml:"issuer" toml:"issuer"`
+	Audience           string `yaml:"audience" toml:"audience"`
+}
+
+type TracingConfig struct {
+	// Exporter is "none", "stdout" or "otlp".
+	Exporter string `yaml:"exporter" toml:"exporter"`
+	// OTLPEndpoint is the host:port of an OTLP/HTTP collector. When empty
+	// the exporter uses the standard OTEL_EXPORTER_OTLP_* variables.
+	OTLPEndpoint string  `yaml:"otlp_endpoint" toml:"otlp_endpoint"`
+	OTLPInsecure bool    `yaml:"otlp_insecure" toml:"otlp_insecure"`
+	ServiceName  string  `yaml:"service_name" toml:"service_name"`
+	SampleRatio  float64 `yaml:"sample_ratio" toml:"sample_ratio"`
+}
+
+type LogConfig struct {
+	// Level is debug, info, warn or error.
+	Level string `yaml:"level" toml:"level"`
+	// Format is json or text.
+	Format string `yaml:"format" toml:"format"`
+	// RedactVariables lists GraphQL variable names, matched as
+	// case-insensitive substrings, whose values are not logged.
+	RedactVariables []string `yaml:"redact_variables" toml:"redact_variables"`
+}
+
+// LimitsConfig bounds the cost of a single GraphQL operation. Zero
+// disables a limit.
+type LimitsConfig struct {
+	MaxDepth      int `yaml:"max_depth" toml:"max_depth"`
+	MaxComplexity int `yaml:"max_complexity" toml:"max_complexity"`
+	MaxAliases    int `yaml:"max_aliases" toml:"max_aliases"`
+}
+
+type RateLimitConfig struct {
+	Enabled bool `yaml:"enabled" toml:"enabled"`
+	// Store is "postgres" or "memory". Empty selects postgres with the
+	// postgres storage and memory otherwise.
+	Store string `yaml:"store" toml:"store"`
+	// Mutations limits each mutation by name; "*" applies to those not
+	// listed.
+	Mutations map[string]Rate `yaml:"mutations" toml:"mutations"`
+	// TrustForwardedFor takes the client IP from X-Forwarded-For, for
+	// deployments behind a proxy that sets it.
+	TrustForwardedFor bool `yaml:"trust_forwarded_for" toml:"trust_forwarded_for"`
+}
+
+// Rate is a token bucket limit written as "burst/period", such as "10/1m":
+// up to 10 requests at once, refilled evenly over a minute.
+type Rate struct {
+	Burst  int
+	Period time.Duration
+}
+
+func ParseRate(s string) (Rate, error) {
+	burst, period, ok := strings.Cut(s, "/")
+	if !ok {
+		return Rate{}, fmt.Errorf("invalid rate %q, expected burst/period such as 10/1m", s)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(burst))
+	if err != nil || n <= 0 {
+		return Rate{}, fmt.Errorf("invalid rate %q: burst must be a positive integer", s)
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(period))
+	if err != nil || d <= 0 {
+		return Rate{}, fmt.Errorf("invalid rate %q: period must be a positive duration", s)
+	}
+	return Rate{Burst: n, Period: d}, nil
+}
+
+func (r *Rate) UnmarshalText(text []byte) error {
+	parsed, err := ParseRate(string(text))
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
+
+func (r Rate) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+func (r Rate) String() string {
+	return strconv.Itoa(r.Burst) + "/" + r.Period.String()
+}
+
+type PersistedQueriesConfig struct {
+	// Manifest is a JSON file mapping query hashes to documents, as
+	// written by "server manifest". When set, other operations are
+	// rejected.
+	Manifest string `yaml:"manifest" toml:"manifest"`
+}
+
+// ContentFiltersConfig sets up the filters comments pass before they are
+// stored. Every Action is reject, flag or rewrite; duplicates cannot be
+// rewritten.
+type ContentFiltersConfig struct {
+	BannedWords BannedWordsFilterConfig `yaml:"banned_words" toml:"banned_words"`
+	Links       LinksFilterConfig       `yaml:"links" toml:"links"`
+	Shouting    ShoutingFilterConfig    `yaml:"shouting" toml:"shouting"`
+	Duplicates  DuplicatesFilterConfig  `yaml:"duplicates" toml:"duplicates"`
+}
+
+type BannedWordsFilterConfig struct {
+	// File lists a word per line; the filter is off without it.
+	File   string `yaml:"file" toml:"file"`
+	Action string `yaml:"action" toml:"action"`
+}
+
+type LinksFilterConfig struct {
+	// Max is the number of links allowed in a comment, -1 to turn the
+	// filter off.
+	Max    int    `yaml:"max" toml:"max"`
+	Action string `yaml:"action" toml:"action"`
+}
+
+type ShoutingFilterConfig struct {
+	MinLetters int `yaml:"min_letters" toml:"min_letters"`
+	// MaxUpperRatio is the share of capital letters allowed, 0 to skip
+	// the check.
+	MaxUpperRatio float64 `yaml:"max_upper_ratio" toml:"max_upper_ratio"`
+	// MaxRepeat is the longest allowed run of one character, 0 to skip
+	// the check.
+	MaxRepeat int    `yaml:"max_repeat" toml:"max_repeat"`
+	Action    string `yaml:"action" toml:"action"`
+}
+
+type DuplicatesFilterConfig struct {
+	// Window is how far back comments of the author are compared, 0 to
+	// turn the filter off.
+	Window time.Duration `yaml:"window" toml:"window"`
+	Action string        `yaml:"action" toml:"action"`
+}
+
+type FeaturesConfig struct {
+	Playground    bool `yaml:"playground" toml:"playground"`
+	Introspection bool `yaml:"introspection" toml:"introspection"`
+	// Metrics serves Prometheus metrics at /metrics.
+	Metrics bool `yaml:"metrics" toml:"metrics"`
+	// AutoMigrate applies pending migrations at startup with the postgres
+	// storage.
+	AutoMigrate bool `yaml:"auto_migrate" toml:"auto_migrate"`
+}
+
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			ListenAddr:         ":8080",
+			ReadHeaderTimeout:  10 * time.Second,
+			WebsocketKeepAlive: 10 * time.Second,
+			ShutdownTimeout:    30 * time.Second,
+			HealthCheckTimeout: 2 * time.Second,
+		},
+		StorageType: "memory",
+		Postgres: PostgresConfig{
+			Host:           "localhost",
+			Port:           5432,
+			User:           "postgres",
+			Password:       "pass",
+			DBName:         "postgres",
+			SSLMode:        "disable",
+			MaxConns:       10,
+			ConnectTimeout: 5 * time.Second,
+			QueryTimeout:   3 * time.Second,
+		},
+		PubSub: PubSubConfig{
+			BufferSize: 64,
+			Overflow:   "coalesce",
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			ServiceName: "posts-comments",
+			SampleRatio: 1,
+		},
+		Log: LogConfig{
+			Level:           "info",
+			Format:          "json",
+			RedactVariables: []string{"password", "secret", "token", "authorization"},
+		},
+		Limits: LimitsConfig{
+			MaxDepth:      15,
+			MaxComplexity: 5000,
+			MaxAliases:    50,
+		},
+		RateLimit: RateLimitConfig{
+			Enabled: true,
+			Mutations: map[string]Rate{
+				"createPost":    {Burst: 10, Period: time.Minute},
+				"createComment": {Burst: 30, Period: time.Minute},
+				"*":             {Burst: 60, Period: time.Minute},
+			},
+		},
+		// Every filter is off until configured.
+		ContentFilters: ContentFiltersConfig{
+			BannedWords: BannedWordsFilterConfig{Action: "reject"},
+			Links:       LinksFilterConfig{Max: -1, Action: "flag"},
+			Shouting:    ShoutingFilterConfig{MinLetters: 20, Action: "rewrite"},
+			Duplicates:  DuplicatesFilterConfig{Action: "reject"},
+		},
+		Features: FeaturesConfig{
+			Playground:    true,
+			Introspection: true,
+			Metrics:       true,
+		},
+	}
+}
+
+var sslModes = map[string]bool{
+	"disable": true, "allow": true, "prefer": true,
+	"require": true, "verify-ca": true, "verify-full": true,
+}
+
+var filterActions = map[string]bool{"reject": true, "flag": true, "rewrite": true}
+
+// Validate reports every invalid setting at once.
+func (c Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	if _, _, err := net.SplitHostPort(c.Server.ListenAddr); err != nil {
+		errs = append(errs, fmt.Errorf("server.listen_addr: %w", err))
+	}
+	check(c.Server.ReadHeaderTimeout > 0, "server.read_header_timeout must be positive")
+	check(c.Server.WebsocketKeepAlive >= 0, "server.websocket_keep_alive must not be negative")
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout must be positive")
+	check(c.Server.HealthCheckTimeout > 0, "server.health_check_timeout must be positive")
+
+	check(c.StorageType == "memory" || c.StorageType == "postgres",
+		"storage_type must be memory or postgres, got %q", c.StorageType)
+
+	pg := c.Postgres
+	if pg.DSN == "" {
+		check(pg.Host != "", "postgres.host is required")
+		check(pg.Port > 0 && pg.Port < 65536, "postgres.port must be between 1 and 65535, got %d", pg.Port)
+		check(pg.DBName != "", "postgres.dbname is required")
+		check(sslModes[pg.SSLMode], "postgres.sslmode %q is not supported", pg.SSLMode)
+	}
+	check(pg.MaxConns > 0, "postgres.max_conns must be positive")
+	check(pg.MinConns >= 0 && pg.MinConns <= pg.MaxConns, "postgres.min_conns must be between 0 and max_conns")
+	check(pg.MaxConnLifetime >= 0, "postgres.max_conn_lifetime must not be negative")
+	check(pg.MaxConnIdleTime >= 0, "postgres.max_conn_idle_time must not be negative")
+	check(pg.ConnectTimeout > 0, "postgres.connect_timeout must be positive")
+	check(pg.QueryTimeout > 0, "postgres.query_timeout must be positive")
+
+	ps := c.PubSub
+	check(ps.Backend == "" || ps.Backend == "memory" || ps.Backend == "postgres",
+		"pubsub.backend must be memory or postgres, got %q", ps.Backend)
+	check(ps.Backend != "postgres" || c.StorageType == "postgres",
+		"pubsub.backend postgres requires the postgres storage")
+	check(ps.BufferSize > 0, "pubsub.buffer_size must be positive")
+	check(ps.Overflow == "coalesce" || ps.Overflow == "disconnect",
+		"pubsub.overflow must be coalesce or disconnect, got %q", ps.Overflow)
+
+	tr := c.Tracing
+	check(tr.Exporter == "none" || tr.Exporter == "stdout" || tr.Exporter == "otlp",
+		"tracing.exporter must be none, stdout or otlp, got %q", tr.Exporter)
+	check(tr.SampleRatio >= 0 && tr.SampleRatio <= 1, "tracing.sample_ratio must be between 0 and 1")
+	check(tr.ServiceName != "", "tracing.service_name is required")
+
+	var level slog.Level
+	check(level.UnmarshalText([]byte(c.Log.Level)) == nil,
+		"log.level must be debug, info, warn or error, got %q", c.Log.Level)
+	check(c.Log.Format == "json" || c.Log.Format == "text",
+		"log.format must be json or text, got %q", c.Log.Format)
+
+	lim := c.Limits
+	check(lim.MaxDepth >= 0, "limits.max_depth must not be negative")
+	check(lim.MaxComplexity >= 0, "limits.max_complexity must not be negative")
+	check(lim.MaxAliases >= 0, "limits.max_aliases must not be negative")
+
+	rl := c.RateLimit
+	check(rl.Store == "" || rl.Store == "memory" || rl.Store == "postgres",
+		"rate_limit.store must be memory or postgres, got %q", rl.Store)
+	check(rl.Store != "postgres" || c.StorageType == "postgres",
+		"rate_limit.store postgres requires the postgres storage")
+	for name, r := range rl.Mutations {
+		check(r.Burst > 0 && r.Period > 0, "rate_limit.mutations.%s must have a positive burst and period", name)
+	}
+
+	cf := c.ContentFilters
+	for name, action := range map[string]string{
+		"banned_words": cf.BannedWords.Action,
+		"links":        cf.Links.Action,
+		"shouting":     cf.Shouting.Action,
+	} {
+		check(filterActions[action], "content_filters.%s.action must be reject, flag or rewrite, got %q", name, action)
+	}
+	check(cf.Duplicates.Action == "reject" || cf.Duplicates.Action == "flag",
+		"content_filters.duplicates.action must be reject or flag, got %q", cf.Duplicates.Action)
+	check(cf.Links.Max >= -1, "content_filters.links.max must be -1 or more")
+	check(cf.Shouting.MinLetters >= 0, "content_filters.shouting.min_letters must not be negative")
+	check(cf.Shouting.MaxUpperRatio >= 0 && cf.Shouting.MaxUpperRatio <= 1,
+		"content_filters.shouting.max_upper_ratio must be between 0 and 1")
+	check(cf.Shouting.MaxRepeat >= 0, "content_filters.shouting.max_repeat must not be negative")
+	check(cf.Duplicates.Window >= 0, "content_filters.duplicates.window must not be negative")
+
+	return errors.Join(errs...)
+}
+
+// ConnString returns the DSN when set, or a postgres:// URL built from the
+// individual settings.
+func (c PostgresConfig) ConnString() string {
+	if c.DSN != "" {
+		return c.DSN
+	}
+
+	q := url.Values{}
+	q.Set("sslmode", c.SSLMode)
+	for k, v := range map[string]string{"sslrootcert": c.SSLRootCert, "sslcert": c.SSLCert, "sslkey": c.SSLKey} {
+		if v != "" {
+			q.Set(k, v)
+		}
+	}
+
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.User, c.Password),
+		Host:     net.JoinHostPort(c.Host, strconv.Itoa(c.Port)),
+		Path:     "/" + c.DBName,
+		RawQuery: q.Encode(),
+	}
+	return u.String()
 }
@@ -0,0 +1,303 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Load builds the configuration for the command-line arguments args
+// (without the program name) and returns the positional arguments left
+// after the flags. The file is taken from -config or CONFIG_FILE.
+func Load(args []string) (*Config, []string, error) {
+	cfg := Default()
+
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "path to a YAML or TOML config file")
+
+	// Flags are applied after the file and the environment, so their values
+	// are only collected while parsing.
+	var fromFlags []func() error
+	for _, b := range bindings(&cfg) {
+		usage := b.usage
+		if b.env != "" {
+			usage += " (env " + b.env + ")"
+		}
+		collect := func(v string) error {
+			fromFlags = append(fromFlags, func() error { return b.apply("-"+b.flag, v) })
+			return nil
+		}
+		if b.isBool {
+			fs.BoolFunc(b.flag, usage, collect)
+		} else {
+			fs.Func(b.flag, usage, collect)
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	if *configFile != "" {
+		if err := loadFile(*configFile, &cfg); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, b := range bindings(&cfg) {
+		if b.env == "" {
+			continue
+		}
+		if v := os.Getenv(b.env); v != "" {
+			if err := b.apply(b.env, v); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	for _, apply := range fromFlags {
+		if err := apply(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return &cfg, fs.Args(), nil
+}
+
+func loadFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("parse config %s: %w", path, err)
+		}
+	case ".toml":
+		md, err := toml.Decode(string(data), cfg)
+		if err != nil {
+			return fmt.Errorf("parse config %s: %w", path, err)
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("parse config %s: unknown key %s", path, undecoded[0])
+		}
+	default:
+		return fmt.Errorf("config %s: unsupported format, use .yaml, .yml or .toml", path)
+	}
+	return nil
+}
+
+// binding ties a setting to its environment variable and flag.
+type binding struct {
+	env    string
+	flag   string
+	usage  string
+	set    func(string) error
+	isBool bool
+}
+
+func bind(env, flag, usage string, set func(string) error) binding {
+	return binding{env: env, flag: flag, usage: usage, set: set}
+}
+
+// boolBind makes a flag that can be given without a value.
+func boolBind(env, flag, usage string, p *bool) binding {
+	return binding{env: env, flag: flag, usage: usage, set: boolean(p), isBool: true}
+}
+
+func (b binding) apply(source, v string) error {
+	if err := b.set(v); err != nil {
+		return fmt.Errorf("%s: %w", source, err)
+	}
+	return nil
+}
+
+func bindings(c *Config) []binding {
+	return []binding{
+		bind("LISTEN_ADDR", "listen", "address to listen on", str(&c.Server.ListenAddr)),
+		bind("READ_HEADER_TIMEOUT", "read-header-timeout", "timeout for reading request headers", duration(&c.Server.ReadHeaderTimeout)),
+		bind("WEBSOCKET_KEEPALIVE", "websocket-keepalive", "websocket ping interval, 0 disables pings", duration(&c.Server.WebsocketKeepAlive)),
+		bind("HEALTH_CHECK_TIMEOUT", "health-check-timeout", "timeout of the readiness checks", duration(&c.Server.HealthCheckTimeout)),
+		bind("SHUTDOWN_TIMEOUT", "shutdown-timeout", "grace period for in-flight requests on shutdown", duration(&c.Server.ShutdownTimeout)),
+
+		bind("STORAGE_TYPE", "storage", "storage backend: memory or postgres", str(&c.StorageType)),
+
+		bind("POSTGRES_DSN", "postgres-dsn", "PostgreSQL connection string, overrides the other connection settings", str(&c.Postgres.DSN)),
+		bind("POSTGRES_HOST", "postgres-host", "PostgreSQL host", str(&c.Postgres.Host)),
+		bind("POSTGRES_PORT", "postgres-port", "PostgreSQL port", integer(&c.Postgres.Port)),
+		bind("POSTGRES_USER", "postgres-user", "PostgreSQL user", str(&c.Postgres.User)),
+		bind("POSTGRES_PASSWORD", "postgres-password", "PostgreSQL password", str(&c.Postgres.Password)),
+		bind("POSTGRES_DB", "postgres-db", "PostgreSQL database", str(&c.Postgres.DBName)),
+		bind("POSTGRES_SSLMODE", "postgres-sslmode", "PostgreSQL sslmode", str(&c.Postgres.SSLMode)),
+		bind("POSTGRES_SSLROOTCERT", "postgres-sslrootcert", "CA certificate file", str(&c.Postgres.SSLRootCert)),
+		bind("POSTGRES_SSLCERT", "postgres-sslcert", "client certificate file", str(&c.Postgres.SSLCert)),
+		bind("POSTGRES_SSLKEY", "postgres-sslkey", "client key file", str(&c.Postgres.SSLKey)),
+		bind("POSTGRES_MAX_CONNS", "postgres-max-conns", "maximum pool size", integer32(&c.Postgres.MaxConns)),
+		bind("POSTGRES_MIN_CONNS", "postgres-min-conns", "minimum pool size", integer32(&c.Postgres.MinConns)),
+		bind("POSTGRES_MAX_CONN_LIFETIME", "postgres-max-conn-lifetime", "maximum lifetime of a pooled connection", duration(&c.Postgres.MaxConnLifetime)),
+		bind("POSTGRES_MAX_CONN_IDLE_TIME", "postgres-max-conn-idle-time", "maximum idle time of a pooled connection", duration(&c.Postgres.MaxConnIdleTime)),
+		bind("POSTGRES_CONNECT_TIMEOUT", "postgres-connect-timeout", "timeout for the initial connection", duration(&c.Postgres.ConnectTimeout)),
+		bind("POSTGRES_QUERY_TIMEOUT", "postgres-query-timeout", "timeout of a single query", duration(&c.Postgres.QueryTimeout)),
+
+		bind("PUBSUB_BACKEND", "pubsub-backend", "subscription delivery: memory or postgres", str(&c.PubSub.Backend)),
+		bind("PUBSUB_BUFFER_SIZE", "pubsub-buffer-size", "events buffered per subscriber", integer(&c.PubSub.BufferSize)),
+		bind("PUBSUB_OVERFLOW", "pubsub-overflow", "slow subscriber policy: coalesce or disconnect", str(&c.PubSub.Overflow)),
+
+		bind("AUTH_HS256_SECRET", "auth-hs256-secret", "HS256 token secret", str(&c.Auth.HS256Secret)),
+		bind("AUTH_RS256_PUBLIC_KEY_FILE", "auth-rs256-public-key-file", "PEM public key for RS256 tokens", str(&c.Auth.RS256PublicKeyFile)),
+		bind("AUTH_JWKS_FILE", "auth-jwks-file", "JWKS file for RS256 tokens", str(&c.Auth.JWKSFile)),
+		bind("AUTH_ISSUER", "auth-issuer", "required token issuer", str(&c.Auth.Issuer)),
+		bind("AUTH_AUDIENCE", "auth-audience", "required token audience", str(&c.Auth.Audience)),
+
+		bind("TRACING_EXPORTER", "tracing-exporter", "trace exporter: none, stdout or otlp", str(&c.Tracing.Exporter)),
+		bind("TRACING_OTLP_ENDPOINT", "tracing-otlp-endpoint", "OTLP/HTTP collector host:port", str(&c.Tracing.OTLPEndpoint)),
+		boolBind("TRACING_OTLP_INSECURE", "tracing-otlp-insecure", "send OTLP over plain HTTP", &c.Tracing.OTLPInsecure),
+		bind("OTEL_SERVICE_NAME", "tracing-service-name", "service name reported in traces", str(&c.Tracing.ServiceName)),
+		bind("TRACING_SAMPLE_RATIO", "tracing-sample-ratio", "share of new traces to sample, 0 to 1", float(&c.Tracing.SampleRatio)),
+
+		bind("LOG_LEVEL", "log-level", "minimum log level: debug, info, warn or error", str(&c.Log.Level)),
+		bind("LOG_FORMAT", "log-format", "log format: json or text", str(&c.Log.Format)),
+		bind("LOG_REDACT_VARIABLES", "log-redact-variables", "comma-separated GraphQL variable names not to log", list(&c.Log.RedactVariables)),
+
+		bind("QUERY_MAX_DEPTH", "query-max-depth", "maximum nesting of a GraphQL query, 0 for no limit", integer(&c.Limits.MaxDepth)),
+		bind("QUERY_MAX_COMPLEXITY", "query-max-complexity", "maximum complexity score of a GraphQL query, 0 for no limit", integer(&c.Limits.MaxComplexity)),
+		bind("QUERY_MAX_ALIASES", "query-max-aliases", "maximum number of aliases in a GraphQL query, 0 for no limit", integer(&c.Limits.MaxAliases)),
+
+		boolBind("RATE_LIMIT_ENABLED", "rate-limit", "rate limit mutations", &c.RateLimit.Enabled),
+		bind("RATE_LIMIT_STORE", "rate-limit-store", "rate limit buckets: memory or postgres", str(&c.RateLimit.Store)),
+		bind("RATE_LIMIT_MUTATIONS", "rate-limit-mutations", "comma-separated mutation=burst/period limits, * for the rest", rates(&c.RateLimit.Mutations)),
+		boolBind("RATE_LIMIT_TRUST_FORWARDED_FOR", "rate-limit-trust-forwarded-for", "take the client IP from X-Forwarded-For", &c.RateLimit.TrustForwardedFor),
+
+		bind("PERSISTED_QUERIES_MANIFEST", "persisted-queries-manifest", "allow only the operations of this manifest", str(&c.PersistedQueries.Manifest)),
+
+		bind("CONTENT_FILTER_BANNED_WORDS_FILE", "content-filter-banned-words-file", "file of banned words, one per line", str(&c.ContentFilters.BannedWords.File)),
+		bind("CONTENT_FILTER_BANNED_WORDS_ACTION", "content-filter-banned-words-action", "action on banned words: reject, flag or rewrite", str(&c.ContentFilters.BannedWords.Action)),
+		bind("CONTENT_FILTER_MAX_LINKS", "content-filter-max-links", "links allowed in a comment, -1 for no limit", integer(&c.ContentFilters.Links.Max)),
+		bind("CONTENT_FILTER_LINKS_ACTION", "content-filter-links-action", "action on too many links: reject, flag or rewrite", str(&c.ContentFilters.Links.Action)),
+		bind("CONTENT_FILTER_SHOUTING_MIN_LETTERS", "content-filter-shouting-min-letters", "letters a comment needs before its case is checked", integer(&c.ContentFilters.Shouting.MinLetters)),
+		bind("CONTENT_FILTER_SHOUTING_MAX_UPPER_RATIO", "content-filter-shouting-max-upper-ratio", "share of capital letters allowed, 0 to skip the check", float(&c.ContentFilters.Shouting.MaxUpperRatio)),
+		bind("CONTENT_FILTER_SHOUTING_MAX_REPEAT", "content-filter-shouting-max-repeat", "longest run of one character allowed, 0 to skip the check", integer(&c.ContentFilters.Shouting.MaxRepeat)),
+		bind("CONTENT_FILTER_SHOUTING_ACTION", "content-filter-shouting-action", "action on shouting: reject, flag or rewrite", str(&c.ContentFilters.Shouting.Action)),
+		bind("CONTENT_FILTER_DUPLICATES_WINDOW", "content-filter-duplicates-window", "window for repeated comments of an author, 0 turns the check off", duration(&c.ContentFilters.Duplicates.Window)),
+		bind("CONTENT_FILTER_DUPLICATES_ACTION", "content-filter-duplicates-action", "action on repeated comments: reject or flag", str(&c.ContentFilters.Duplicates.Action)),
+
+		boolBind("PLAYGROUND_ENABLED", "playground", "serve the GraphQL playground at /", &c.Features.Playground),
+		boolBind("INTROSPECTION_ENABLED", "introspection", "allow schema introspection", &c.Features.Introspection),
+		boolBind("METRICS_ENABLED", "metrics", "serve Prometheus metrics at /metrics", &c.Features.Metrics),
+		boolBind("AUTO_MIGRATE", "auto-migrate", "apply pending migrations at startup", &c.Features.AutoMigrate),
+	}
+}
+
+func str(p *string) func(string) error {
+	return func(v string) error {
+		*p = v
+		return nil
+	}
+}
+
+// list splits a comma-separated value, dropping empty items.
+func list(p *[]string) func(string) error {
+	return func(v string) error {
+		var items []string
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		*p = items
+		return nil
+	}
+}
+
+// rates parses comma-separated name=rate pairs, replacing the whole map.
+func rates(p *map[string]Rate) func(string) error {
+	return func(v string) error {
+		m := make(map[string]Rate)
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			name, rate, ok := strings.Cut(item, "=")
+			if !ok {
+				return fmt.Errorf("invalid limit %q, expected name=burst/period", item)
+			}
+			r, err := ParseRate(rate)
+			if err != nil {
+				return err
+			}
+			m[strings.TrimSpace(name)] = r
+		}
+		*p = m
+		return nil
+	}
+}
+
+func integer(p *int) func(string) error {
+	return func(v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", v)
+		}
+		*p = n
+		return nil
+	}
+}
+
+func integer32(p *int32) func(string) error {
+	return func(v string) error {
+		n, err := strconv.ParseInt(v, 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", v)
+		}
+		*p = int32(n)
+		return nil
+	}
+}
+
+func float(p *float64) func(string) error {
+	return func(v string) error {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", v)
+		}
+		*p = f
+		return nil
+	}
+}
+
+func duration(p *time.Duration) func(string) error {
+	return func(v string) error {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", v)
+		}
+		*p = d
+		return nil
+	}
+}
+
+func boolean(p *bool) func(string) error {
+	return func(v string) error {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", v)
+		}
+		*p = b
+		return nil
+	}
+}
@@ -13,4 +13,52 @@ type Comment struct {
 	ParentID  *uuid.UUID
 	Content   string
 	CreatedAt time.Time
+	// EditedAt is the time of the last edit, nil for comments never edited.
+	EditedAt *time.Time
+	// RevisionCount is the number of edits, each of which left a
+	// CommentRevision.
+	RevisionCount int
+	// DeletedAt is set on soft-deleted comments, which stay in their thread
+	// as tombstones.
+	DeletedAt *time.Time
+	DeletedBy uuid.UUID
+	Status    ModerationStatus
+}
+
+// ModerationStatus is the outcome of moderation of a comment.
+type ModerationStatus string
+
+const (
+	StatusVisible ModerationStatus = "visible"
+	// StatusPending comments were flagged and await review. They stay
+	// visible meanwhile.
+	StatusPending ModerationStatus = "pending"
+	// StatusHidden comments are left out of comment lists for users other
+	// than moderators.
+	StatusHidden ModerationStatus = "hidden"
+	// StatusRemoved comments stay in their thread with the content replaced
+	// for users other than moderators.
+	StatusRemoved ModerationStatus = "removed"
+)
+
+// Contents shown in place of the content of deleted and moderated comments.
+const (
+	TombstoneContent = "[deleted]"
+	RemovedContent   = "[removed]"
+	HiddenContent    = "[hidden]"
+)
+
+func (c Comment) Deleted() bool {
+	return c.DeletedAt != nil
+}
+
+// CommentRevision is the content a comment had before one of its edits.
+type CommentRevision struct {
+	ID        uuid.UUID
+	CommentID uuid.UUID
+	// Content is the content replaced by the edit.
+	Content  string
+	EditorID uuid.UUID
+	// CreatedAt is the time of the edit.
+	CreatedAt time.Time
 }
@@ -0,0 +1,63 @@
+package domain
+
+import "errors"
+
+// MaxCommentLength is the maximum comment length in bytes.
+const MaxCommentLength = 2000
+
+// ErrorKind classifies domain errors so that transport layers can map them
+// to their own error codes without matching on messages.
+type ErrorKind string
+
+const (
+	KindNotFound   ErrorKind = "NOT_FOUND"
+	KindValidation ErrorKind = "VALIDATION"
+	KindForbidden  ErrorKind = "FORBIDDEN"
+
+	KindUnauthenticated ErrorKind = "UNAUTHENTICATED"
+)
+
+// Error is an error returned by every storage backend and resolver for
+// expected failures. Sentinel values below are compared with errors.Is.
+type Error struct {
+	Kind    ErrorKind
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func NewError(kind ErrorKind, message string) *Error {
+	return &Error{Kind: kind, Message: message}
+}
+
+var (
+	ErrPostNotFound           = NewError(KindNotFound, "post not found")
+	ErrCommentNotFound        = NewError(KindNotFound, "comment not found")
+	ErrParentCommentNotFound  = NewError(KindNotFound, "parent comment not found")
+	ErrParentCommentWrongPost = NewError(KindValidation, "parent comment belongs to another post")
+	ErrParentCommentDeleted   = NewError(KindValidation, "parent comment is deleted")
+	ErrCommentDeleted         = NewError(KindForbidden, "comment is deleted")
+	ErrCommentTooLong         = NewError(KindValidation, "comment content exceeds maximum length")
+	ErrCommentsDisabled       = NewError(KindForbidden, "comments are disabled")
+	ErrUserNotFound           = NewError(KindNotFound, "user not found")
+	ErrUnauthenticated        = NewError(KindUnauthenticated, "authentication required")
+	ErrNotAuthor              = NewError(KindForbidden, "only the author can do this")
+	ErrNotAuthorOrModerator   = NewError(KindForbidden, "only the author or a moderator can do this")
+	ErrNotAdmin               = NewError(KindForbidden, "only an admin can do this")
+	ErrNotModerator           = NewError(KindForbidden, "only a moderator can do this")
+	ErrFlagNotFound           = NewError(KindNotFound, "flag not found")
+	ErrFlagResolved           = NewError(KindValidation, "flag is already resolved")
+	ErrInvalidResolution      = NewError(KindValidation, "a flag is resolved as visible, hidden or removed")
+)
+
+// KindOf returns the kind of the first *Error in err's chain, or an empty
+// kind if err is not a domain error.
+func KindOf(err error) ErrorKind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	return ""
+}
@@ -0,0 +1,35 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FilterAction is what a content filter does with a comment it catches.
+type FilterAction string
+
+const (
+	// FilterReject refuses the comment.
+	FilterReject FilterAction = "reject"
+	// FilterFlag stores the comment as pending and adds a flag to the
+	// moderation queue.
+	FilterFlag FilterAction = "flag"
+	// FilterRewrite stores the comment with the content changed by the
+	// filter.
+	FilterRewrite FilterAction = "rewrite"
+)
+
+// FilterDecision records a content filter catching a comment, kept for
+// auditing. Rejected comments are never stored, so CommentID may refer to
+// no comment.
+type FilterDecision struct {
+	ID        uuid.UUID
+	CommentID uuid.UUID
+	PostID    uuid.UUID
+	AuthorID  uuid.UUID
+	Filter    string
+	Action    FilterAction
+	Reason    string
+	CreatedAt time.Time
+}
@@ -0,0 +1,34 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MaxFlagReasonLength is the maximum flag reason length in characters.
+const MaxFlagReasonLength = 500
+
+// CommentFlag is a report of a comment for moderator review.
+type CommentFlag struct {
+	ID         uuid.UUID
+	CommentID  uuid.UUID
+	ReporterID uuid.UUID
+	Reason     string
+	CreatedAt  time.Time
+	// ResolvedAt is set once a moderator decided on the comment, giving it
+	// the Resolution status.
+	ResolvedAt *time.Time
+	ResolvedBy uuid.UUID
+	Resolution ModerationStatus
+}
+
+func (f CommentFlag) Resolved() bool {
+	return f.ResolvedAt != nil
+}
+
+// ValidResolution reports whether a moderator may give a flagged comment
+// status s.
+func ValidResolution(s ModerationStatus) bool {
+	return s == StatusVisible || s == StatusHidden || s == StatusRemoved
+}
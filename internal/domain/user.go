@@ -0,0 +1,30 @@
+package domain
+
+import (
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Role grants privileges over content of other users.
+type Role string
+
+const (
+	RoleModerator Role = "moderator"
+	// RoleAdmin has every privilege of RoleModerator.
+	RoleAdmin Role = "admin"
+)
+
+type User struct {
+	ID   uuid.UUID
+	Name string
+	// Roles come from the token of each request and are not stored.
+	Roles     []Role
+	CreatedAt time.Time
+}
+
+// HasRole reports whether u has role r, which admins have for every r.
+func (u User) HasRole(r Role) bool {
+	return slices.Contains(u.Roles, r) || slices.Contains(u.Roles, RoleAdmin)
+}
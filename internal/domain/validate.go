@@ -0,0 +1,50 @@
+package domain
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxTitleLength is the maximum post title length in characters.
+const MaxTitleLength = 300
+
+var (
+	ErrEmptyTitle    = NewError(KindValidation, "title must not be empty")
+	ErrTitleTooLong  = NewError(KindValidation, "title exceeds maximum length")
+	ErrEmptyContent  = NewError(KindValidation, "content must not be empty")
+	ErrEmptyReason   = NewError(KindValidation, "reason must not be empty")
+	ErrReasonTooLong = NewError(KindValidation, "reason exceeds maximum length")
+)
+
+func ValidatePost(p Post) error {
+	if strings.TrimSpace(p.Title) == "" {
+		return ErrEmptyTitle
+	}
+	if utf8.RuneCountInString(p.Title) > MaxTitleLength {
+		return ErrTitleTooLong
+	}
+	if strings.TrimSpace(p.Content) == "" {
+		return ErrEmptyContent
+	}
+	return nil
+}
+
+func ValidateCommentContent(content string) error {
+	if strings.TrimSpace(content) == "" {
+		return ErrEmptyContent
+	}
+	if len(content) > MaxCommentLength {
+		return ErrCommentTooLong
+	}
+	return nil
+}
+
+func ValidateFlagReason(reason string) error {
+	if strings.TrimSpace(reason) == "" {
+		return ErrEmptyReason
+	}
+	if utf8.RuneCountInString(reason) > MaxFlagReasonLength {
+		return ErrReasonTooLong
+	}
+	return nil
+}
@@ -0,0 +1,274 @@
+package filter
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"posts-comments-1/internal/domain"
+)
+
+// BannedWords catches comments containing any of a list of words, matched
+// as whole words regardless of case. Rewriting masks them with asterisks.
+type BannedWords struct {
+	words  map[string]bool
+	action domain.FilterAction
+}
+
+func NewBannedWords(words []string, action domain.FilterAction) (*BannedWords, error) {
+	if err := checkAction("banned_words", action, true); err != nil {
+		return nil, err
+	}
+	f := &BannedWords{words: make(map[string]bool, len(words)), action: action}
+	for _, w := range words {
+		f.words[strings.ToLower(w)] = true
+	}
+	return f, nil
+}
+
+// LoadWords reads a word list with a word per line. Empty lines and lines
+// starting with # are skipped.
+func LoadWords(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("load word list: %w", err)
+	}
+	defer f.Close()
+
+	var words []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words = append(words, line)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("load word list %s: %w", path, err)
+	}
+	return words, nil
+}
+
+func (*BannedWords) Name() string { return "banned_words" }
+
+func (f *BannedWords) Check(_ context.Context, in Input) (*Decision, error) {
+	var (
+		found []string
+		b     strings.Builder
+		last  int
+	)
+	// Words are runs of letters and digits, which unlike \b in regexp also
+	// holds for non-Latin scripts.
+	start := -1
+	for i, r := range in.Content + " " {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start < 0 {
+			continue
+		}
+		word := in.Content[start:i]
+		if w := strings.ToLower(word); f.words[w] {
+			if !slices.Contains(found, w) {
+				found = append(found, w)
+			}
+			b.WriteString(in.Content[last:start])
+			b.WriteString(strings.Repeat("*", utf8.RuneCountInString(word)))
+			last = i
+		}
+		start = -1
+	}
+	if len(found) == 0 {
+		return nil, nil
+	}
+	b.WriteString(in.Content[last:])
+
+	return &Decision{
+		Action:  f.action,
+		Reason:  "banned words: " + strings.Join(found, ", "),
+		Content: b.String(),
+	}, nil
+}
+
+var linkPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)[^\s<>"]+`)
+
+// Links catches comments with more than max links, counting http(s)://
+// and www. addresses. Rewriting replaces the links past the limit.
+type Links struct {
+	max    int
+	action domain.FilterAction
+}
+
+// RemovedLink replaces the links dropped by Links.
+const RemovedLink = "[link removed]"
+
+func NewLinks(max int, action domain.FilterAction) (*Links, error) {
+	if max < 0 {
+		return nil, fmt.Errorf("links filter: max must not be negative")
+	}
+	if err := checkAction("links", action, true); err != nil {
+		return nil, err
+	}
+	return &Links{max: max, action: action}, nil
+}
+
+func (*Links) Name() string { return "links" }
+
+func (f *Links) Check(_ context.Context, in Input) (*Decision, error) {
+	n := 0
+	rewritten := linkPattern.ReplaceAllStringFunc(in.Content, func(link string) string {
+		n++
+		if n > f.max {
+			return RemovedLink
+		}
+		return link
+	})
+	if n <= f.max {
+		return nil, nil
+	}
+	return &Decision{
+		Action:  f.action,
+		Reason:  fmt.Sprintf("too many links (%d, at most %d)", n, f.max),
+		Content: rewritten,
+	}, nil
+}
+
+// Shouting catches comments mostly in capital letters and comments
+// repeating a character many times in a row. Rewriting lowercases the
+// comment and shortens the repeats.
+type Shouting struct {
+	opts ShoutingOptions
+}
+
+type ShoutingOptions struct {
+	// MinLetters is the number of letters a comment needs before its case
+	// is checked, so short acronyms pass.
+	MinLetters int
+	// MaxUpperRatio is the share of capital letters allowed, 0 to skip
+	// the check.
+	MaxUpperRatio float64
+	// MaxRepeat is the longest allowed run of one character, 0 to skip
+	// the check.
+	MaxRepeat int
+	Action    domain.FilterAction
+}
+
+func NewShouting(opts ShoutingOptions) (*Shouting, error) {
+	if opts.MinLetters < 0 || opts.MaxRepeat < 0 || opts.MaxUpperRatio < 0 || opts.MaxUpperRatio > 1 {
+		return nil, fmt.Errorf("shouting filter: min_letters and max_repeat must not be negative, max_upper_ratio must be between 0 and 1")
+	}
+	if err := checkAction("shouting", opts.Action, true); err != nil {
+		return nil, err
+	}
+	return &Shouting{opts: opts}, nil
+}
+
+func (*Shouting) Name() string { return "shouting" }
+
+func (f *Shouting) Check(_ context.Context, in Input) (*Decision, error) {
+	var reasons []string
+	content := in.Content
+
+	if f.opts.MaxUpperRatio > 0 {
+		letters, upper := 0, 0
+		for _, r := range content {
+			if unicode.IsLetter(r) {
+				letters++
+				if unicode.IsUpper(r) {
+					upper++
+				}
+			}
+		}
+		if letters > 0 && letters >= f.opts.MinLetters && float64(upper)/float64(letters) > f.opts.MaxUpperRatio {
+			reasons = append(reasons, "too many capital letters")
+			content = strings.ToLower(content)
+		}
+	}
+
+	if f.opts.MaxRepeat > 0 {
+		var (
+			b        strings.Builder
+			prev     rune
+			run      int
+			repeated bool
+		)
+		for _, r := range content {
+			if r == prev {
+				run++
+			} else {
+				prev, run = r, 1
+			}
+			if run > f.opts.MaxRepeat && !unicode.IsSpace(r) {
+				repeated = true
+				continue
+			}
+			b.WriteRune(r)
+		}
+		if repeated {
+			reasons = append(reasons, fmt.Sprintf("characters repeated more than %d times", f.opts.MaxRepeat))
+			content = b.String()
+		}
+	}
+
+	if len(reasons) == 0 {
+		return nil, nil
+	}
+	return &Decision{
+		Action:  f.opts.Action,
+		Reason:  strings.Join(reasons, "; "),
+		Content: content,
+	}, nil
+}
+
+// RecentComments looks up earlier comments of an author; storage.Storage
+// implements it.
+type RecentComments interface {
+	HasRecentComment(ctx context.Context, authorID uuid.UUID, content string, since time.Time) (bool, error)
+}
+
+// Duplicates catches new comments repeating a comment their author wrote
+// within the window. Anonymous comments and edits are not checked.
+type Duplicates struct {
+	comments RecentComments
+	window   time.Duration
+	action   domain.FilterAction
+}
+
+func NewDuplicates(comments RecentComments, window time.Duration, action domain.FilterAction) (*Duplicates, error) {
+	if window <= 0 {
+		return nil, fmt.Errorf("duplicates filter: window must be positive")
+	}
+	if err := checkAction("duplicates", action, false); err != nil {
+		return nil, err
+	}
+	return &Duplicates{comments: comments, window: window, action: action}, nil
+}
+
+func (*Duplicates) Name() string { return "duplicates" }
+
+func (f *Duplicates) Check(ctx context.Context, in Input) (*Decision, error) {
+	if in.Edit || in.AuthorID == uuid.Nil {
+		return nil, nil
+	}
+	dup, err := f.comments.HasRecentComment(ctx, in.AuthorID, in.Content, time.Now().Add(-f.window))
+	if err != nil || !dup {
+		return nil, err
+	}
+	return &Decision{
+		Action: f.action,
+		Reason: fmt.Sprintf("same comment posted within %s", f.window),
+	}, nil
+}
@@ -0,0 +1,106 @@
+// Package filter runs comments through content filters before they are
+// stored. A filter that catches a comment rejects it, flags it for review
+// or rewrites its content, as configured for that filter.
+package filter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"posts-comments-1/internal/domain"
+	"posts-comments-1/internal/logging"
+)
+
+// Input is a comment about to be stored.
+type Input struct {
+	CommentID uuid.UUID
+	PostID    uuid.UUID
+	AuthorID  uuid.UUID
+	Content   string
+	// Edit is set when an existing comment gets new content.
+	Edit bool
+}
+
+// Decision is a filter catching a comment.
+type Decision struct {
+	Filter string
+	Action domain.FilterAction
+	Reason string
+	// Content is the rewritten content for FilterRewrite.
+	Content string
+}
+
+// Filter checks comments. Check returns nil for comments it lets through.
+type Filter interface {
+	Name() string
+	Check(ctx context.Context, in Input) (*Decision, error)
+}
+
+// Result is the outcome of a Pipeline run.
+type Result struct {
+	// Content is the content to store, after every rewrite.
+	Content   string
+	Decisions []Decision
+}
+
+// Flagged reports whether a filter flagged the comment for review.
+func (r Result) Flagged() bool {
+	for _, d := range r.Decisions {
+		if d.Action == domain.FilterFlag {
+			return true
+		}
+	}
+	return false
+}
+
+// Pipeline runs filters in order. Rewritten content is passed on to the
+// filters that follow; the first rejection stops the run.
+type Pipeline struct {
+	Filters []Filter
+}
+
+// Run checks in and returns a validation error if a filter rejects it.
+// The decisions made up to the rejection are returned either way. A
+// filter that fails is logged and skipped, so an unavailable dependency
+// does not stop comments.
+func (p *Pipeline) Run(ctx context.Context, in Input) (Result, error) {
+	res := Result{Content: in.Content}
+	for _, f := range p.Filters {
+		in.Content = res.Content
+		d, err := f.Check(ctx, in)
+		if err != nil {
+			logging.FromContext(ctx).ErrorContext(ctx, "content filter failed, skipping",
+				"filter", f.Name(), "comment_id", in.CommentID, "error", err)
+			continue
+		}
+		if d == nil {
+			continue
+		}
+
+		d.Filter = f.Name()
+		res.Decisions = append(res.Decisions, *d)
+		switch d.Action {
+		case domain.FilterReject:
+			return res, domain.NewError(domain.KindValidation, fmt.Sprintf("comment rejected: %s", d.Reason))
+		case domain.FilterRewrite:
+			res.Content = d.Content
+		}
+	}
+	return res, nil
+}
+
+// checkAction reports an action a filter does not support.
+func checkAction(filter string, action domain.FilterAction, rewrite bool) error {
+	switch action {
+	case domain.FilterReject, domain.FilterFlag:
+		return nil
+	case domain.FilterRewrite:
+		if rewrite {
+			return nil
+		}
+		return fmt.Errorf("%s filter cannot rewrite content", filter)
+	}
+	return fmt.Errorf("%s filter: unknown action %q, expected reject, flag or rewrite", filter, action)
+}
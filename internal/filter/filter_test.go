@@ -0,0 +1,138 @@
+package filter
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"posts-comments-1/internal/domain"
+)
+
+func TestBannedWords_Check(t *testing.T) {
+	f, err := NewBannedWords([]string{"spam", "Хлам"}, domain.FilterRewrite)
+	if err != nil {
+		t.Fatalf("NewBannedWords error: %v", err)
+	}
+
+	d, err := f.Check(context.Background(), Input{Content: "No SPAM, no хлам, but spammer is fine"})
+	if err != nil {
+		t.Fatalf("Check error: %v", err)
+	}
+	if d == nil || d.Content != "No ****, no ****, but spammer is fine" {
+		t.Fatalf("unexpected decision %+v", d)
+	}
+	if d.Reason != "banned words: spam, хлам" {
+		t.Fatalf("unexpected reason %q", d.Reason)
+	}
+
+	if d, _ := f.Check(context.Background(), Input{Content: "all clean"}); d != nil {
+		t.Fatalf("expected no decision, got %+v", d)
+	}
+}
+
+func TestLoadWords_SkipsComments(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "words.txt")
+	if err := os.WriteFile(path, []byte("# banned\nspam\n\n  scam  \n"), 0o644); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+	words, err := LoadWords(path)
+	if err != nil {
+		t.Fatalf("LoadWords error: %v", err)
+	}
+	if strings.Join(words, ",") != "spam,scam" {
+		t.Fatalf("unexpected words %v", words)
+	}
+}
+
+func TestLinks_Check(t *testing.T) {
+	f, err := NewLinks(1, domain.FilterRewrite)
+	if err != nil {
+		t.Fatalf("NewLinks error: %v", err)
+	}
+
+	d, _ := f.Check(context.Background(), Input{Content: "see https://a.example and www.b.example"})
+	if d == nil || d.Content != "see https://a.example and "+RemovedLink {
+		t.Fatalf("unexpected decision %+v", d)
+	}
+	if d, _ := f.Check(context.Background(), Input{Content: "see https://a.example"}); d != nil {
+		t.Fatalf("expected no decision, got %+v", d)
+	}
+}
+
+func TestShouting_Check(t *testing.T) {
+	f, err := NewShouting(ShoutingOptions{MinLetters: 10, MaxUpperRatio: 0.5, MaxRepeat: 3, Action: domain.FilterRewrite})
+	if err != nil {
+		t.Fatalf("NewShouting error: %v", err)
+	}
+
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{content: "THIS IS A GREAT POST", want: "this is a great post"},
+		{content: "nooooooo!!!!!", want: "nooo!!!"},
+		{content: "NASA rocks", want: ""},
+		{content: "ok    then", want: ""},
+	}
+	for _, tt := range tests {
+		d, err := f.Check(context.Background(), Input{Content: tt.content})
+		if err != nil {
+			t.Fatalf("%q: Check error: %v", tt.content, err)
+		}
+		if tt.want == "" {
+			if d != nil {
+				t.Fatalf("%q: expected no decision, got %+v", tt.content, d)
+			}
+			continue
+		}
+		if d == nil || d.Content != tt.want {
+			t.Fatalf("%q: expected %q, got %+v", tt.content, tt.want, d)
+		}
+	}
+}
+
+type recentComments map[string]bool
+
+func (r recentComments) HasRecentComment(_ context.Context, _ uuid.UUID, content string, _ time.Time) (bool, error) {
+	return r[content], nil
+}
+
+func TestPipeline_Run(t *testing.T) {
+	if _, err := NewDuplicates(recentComments{}, time.Minute, domain.FilterRewrite); err == nil {
+		t.Fatal("expected duplicates to refuse rewriting")
+	}
+	words, _ := NewBannedWords([]string{"spam"}, domain.FilterRewrite)
+	links, _ := NewLinks(0, domain.FilterFlag)
+	dups, _ := NewDuplicates(recentComments{"****": true}, time.Minute, domain.FilterReject)
+	p := &Pipeline{Filters: []Filter{words, links, dups}}
+	author := uuid.New()
+
+	res, err := p.Run(context.Background(), Input{AuthorID: author, Content: "spam at www.example.com"})
+	if err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+	if res.Content != "**** at www.example.com" || !res.Flagged() || len(res.Decisions) != 2 {
+		t.Fatalf("unexpected result %+v", res)
+	}
+	if res.Decisions[0].Filter != "banned_words" || res.Decisions[1].Filter != "links" {
+		t.Fatalf("unexpected decisions %+v", res.Decisions)
+	}
+
+	// The rewritten content is what the duplicate check sees.
+	res, err = p.Run(context.Background(), Input{AuthorID: author, Content: "spam"})
+	if domain.KindOf(err) != domain.KindValidation {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+	if n := len(res.Decisions); n != 2 || res.Decisions[n-1].Action != domain.FilterReject {
+		t.Fatalf("expected the rejection to be recorded, got %+v", res.Decisions)
+	}
+
+	if _, err := p.Run(context.Background(), Input{AuthorID: author, Content: "spam", Edit: true}); err != nil {
+		t.Fatalf("expected edits to skip the duplicate check, got %v", err)
+	}
+}
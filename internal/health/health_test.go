@@ -0,0 +1,67 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func ready(t *testing.T, c *Checker) (int, Report) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	c.ReadyHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	var report Report
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatalf("decode report: %v", err)
+	}
+	return rec.Code, report
+}
+
+func TestReadyHandler(t *testing.T) {
+	c := New(time.Second)
+	c.Add("storage", func(context.Context) error { return nil })
+
+	code, report := ready(t, c)
+	if code != http.StatusOK || report.Status != StatusOK || report.Components["storage"].Status != StatusOK {
+		t.Fatalf("expected ready, got %d %+v", code, report)
+	}
+
+	c.Add("migrations", func(context.Context) error { return errors.New("2 pending migrations") })
+	code, report = ready(t, c)
+	if code != http.StatusServiceUnavailable || report.Status != StatusUnavailable {
+		t.Fatalf("expected unavailable, got %d %+v", code, report)
+	}
+	if got := report.Components["migrations"]; got.Status != StatusUnavailable || got.Error != "2 pending migrations" {
+		t.Fatalf("unexpected migrations component: %+v", got)
+	}
+	if report.Components["storage"].Status != StatusOK {
+		t.Fatalf("unexpected storage component: %+v", report.Components["storage"])
+	}
+}
+
+func TestReady_Timeout(t *testing.T) {
+	c := New(10 * time.Millisecond)
+	c.Add("storage", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	if report := c.Ready(context.Background()); report.Status != StatusUnavailable {
+		t.Fatalf("expected unavailable, got %+v", report)
+	}
+}
+
+func TestReady_Drain(t *testing.T) {
+	c := New(time.Second)
+	c.Drain()
+
+	code, report := ready(t, c)
+	if code != http.StatusServiceUnavailable || report.Components["server"].Status != StatusUnavailable {
+		t.Fatalf("expected unavailable while draining, got %d %+v", code, report)
+	}
+}
@@ -0,0 +1,181 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+const (
+	redacted = "[REDACTED]"
+	// maxLoggedString truncates long variable values such as comment
+	// content.
+	maxLoggedString = 200
+)
+
+// GraphQL is a gqlgen extension that logs every query and mutation once
+// it is answered, and every subscription once it ends, with the operation
+// name, variables, duration and error codes.
+type GraphQL struct {
+	// RedactVariables lists variable names whose values are replaced in
+	// the log. Names match case-insensitively as substrings, at any depth
+	// of input objects.
+	RedactVariables []string
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationInterceptor
+	graphql.ResponseInterceptor
+} = GraphQL{}
+
+func (GraphQL) ExtensionName() string {
+	return "Logging"
+}
+
+func (GraphQL) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+// subscriptionLog collects the error codes sent over a subscription until
+// it ends.
+type subscriptionLog struct {
+	mu    sync.Mutex
+	codes []string
+}
+
+type subscriptionLogKey struct{}
+
+func (g GraphQL) InterceptOperation(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+	oc := graphql.GetOperationContext(ctx)
+	if oc.Operation == nil || oc.Operation.Operation != ast.Subscription {
+		return next(ctx)
+	}
+
+	sl := &subscriptionLog{}
+	ctx = context.WithValue(ctx, subscriptionLogKey{}, sl)
+	FromContext(ctx).DebugContext(ctx, "subscription started", g.operationAttrs(oc)...)
+
+	responses := next(ctx)
+	return func(ctx context.Context) *graphql.Response {
+		resp := responses(ctx)
+		if resp != nil {
+			sl.mu.Lock()
+			sl.codes = append(sl.codes, errorCodes(resp)...)
+			sl.mu.Unlock()
+			return resp
+		}
+
+		sl.mu.Lock()
+		codes := sl.codes
+		sl.mu.Unlock()
+		g.log(ctx, "subscription ended", oc, codes)
+		return nil
+	}
+}
+
+// InterceptResponse logs queries and mutations, including those rejected
+// before execution, such as by validation.
+func (g GraphQL) InterceptResponse(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	resp := next(ctx)
+	if resp == nil || !graphql.HasOperationContext(ctx) {
+		return resp
+	}
+	if _, ok := ctx.Value(subscriptionLogKey{}).(*subscriptionLog); ok {
+		return resp
+	}
+
+	g.log(ctx, "graphql operation", graphql.GetOperationContext(ctx), errorCodes(resp))
+	return resp
+}
+
+func (g GraphQL) log(ctx context.Context, msg string, oc *graphql.OperationContext, codes []string) {
+	attrs := g.operationAttrs(oc)
+	attrs = append(attrs, slog.Duration("duration", time.Since(oc.Stats.OperationStart)))
+
+	level := slog.LevelInfo
+	if len(codes) > 0 {
+		level = slog.LevelWarn
+		attrs = append(attrs, slog.Any("error_codes", codes))
+	}
+	FromContext(ctx).Log(ctx, level, msg, attrs...)
+}
+
+func (g GraphQL) operationAttrs(oc *graphql.OperationContext) []any {
+	name, typ := oc.OperationName, "unknown"
+	if oc.Operation != nil {
+		typ = string(oc.Operation.Operation)
+		if oc.Operation.Name != "" {
+			name = oc.Operation.Name
+		}
+	}
+	if name == "" {
+		name = "anonymous"
+	}
+
+	attrs := []any{slog.String("operation", name), slog.String("type", typ)}
+	if len(oc.Variables) > 0 {
+		attrs = append(attrs, slog.Any("variables", g.redact(oc.Variables)))
+	}
+	return attrs
+}
+
+// redact returns a copy of vars safe for logging.
+func (g GraphQL) redact(vars map[string]any) map[string]any {
+	out := make(map[string]any, len(vars))
+	for k, v := range vars {
+		if g.sensitive(k) {
+			out[k] = redacted
+			continue
+		}
+		out[k] = g.redactValue(v)
+	}
+	return out
+}
+
+func (g GraphQL) redactValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		return g.redact(v)
+	case []any:
+		out := make([]any, len(v))
+		for i := range v {
+			out[i] = g.redactValue(v[i])
+		}
+		return out
+	case string:
+		if r := []rune(v); len(r) > maxLoggedString {
+			return string(r[:maxLoggedString]) + "…"
+		}
+	}
+	return v
+}
+
+func (g GraphQL) sensitive(name string) bool {
+	name = strings.ToLower(name)
+	for _, s := range g.RedactVariables {
+		if s != "" && strings.Contains(name, strings.ToLower(s)) {
+			return true
+		}
+	}
+	return false
+}
+
+// errorCodes returns the extensions.code of every error in resp, INTERNAL
+// for errors without one.
+func errorCodes(resp *graphql.Response) []string {
+	var codes []string
+	for _, err := range resp.Errors {
+		code, _ := err.Extensions["code"].(string)
+		if code == "" {
+			code = "INTERNAL"
+		}
+		codes = append(codes, code)
+	}
+	return codes
+}
@@ -0,0 +1,40 @@
+// Package logging sets up structured logging with log/slog and carries a
+// per-request logger, tagged with the request ID, through contexts.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+
+	"posts-comments-1/internal/config"
+)
+
+// New returns a logger writing to w in the configured format and level.
+// The config is expected to be validated.
+func New(w io.Writer, cfg config.LogConfig) *slog.Logger {
+	var level slog.Level
+	_ = level.UnmarshalText([]byte(cfg.Level))
+
+	opts := &slog.HandlerOptions{Level: level}
+	if cfg.Format == "text" {
+		return slog.New(slog.NewTextHandler(w, opts))
+	}
+	return slog.New(slog.NewJSONHandler(w, opts))
+}
+
+type loggerKey struct{}
+
+// WithLogger returns a copy of ctx carrying l.
+func WithLogger(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// FromContext returns the logger of the request ctx belongs to, or the
+// default logger outside of requests.
+func FromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return slog.Default()
+}
@@ -0,0 +1,98 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+func TestMiddleware_RequestID(t *testing.T) {
+	var buf bytes.Buffer
+	base := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	var gotID string
+	h := Middleware(base, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotID = RequestID(r.Context())
+		FromContext(r.Context()).Info("hello")
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/query", nil))
+	id := rec.Header().Get(RequestIDHeader)
+	if id == "" || id != gotID {
+		t.Fatalf("expected a generated ID in the header and context, got %q and %q", id, gotID)
+	}
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
+	}
+	if line["request_id"] != id {
+		t.Fatalf("expected request_id %q in the log, got %v", id, line["request_id"])
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/query", nil)
+	req.Header.Set(RequestIDHeader, "from-proxy")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if got := rec.Header().Get(RequestIDHeader); got != "from-proxy" {
+		t.Fatalf("expected the incoming ID to be kept, got %q", got)
+	}
+
+	req.Header.Set(RequestIDHeader, "bad\nid")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if got := rec.Header().Get(RequestIDHeader); got == "bad\nid" {
+		t.Fatal("expected an invalid incoming ID to be replaced")
+	}
+}
+
+func TestGraphQL_LogsOperation(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := WithLogger(context.Background(), slog.New(slog.NewJSONHandler(&buf, nil)))
+	ctx = graphql.WithOperationContext(ctx, &graphql.OperationContext{
+		Operation: &ast.OperationDefinition{Operation: ast.Mutation, Name: "Login"},
+		Variables: map[string]any{
+			"input": map[string]any{"name": "alice", "Password": "hunter2"},
+			"token": "abc",
+		},
+		Stats: graphql.Stats{OperationStart: time.Now()},
+	})
+
+	ext := GraphQL{RedactVariables: []string{"password", "token"}}
+	ext.InterceptResponse(ctx, func(context.Context) *graphql.Response {
+		return &graphql.Response{Errors: gqlerror.List{
+			{Message: "no", Extensions: map[string]any{"code": "FORBIDDEN"}},
+			{Message: "boom"},
+		}}
+	})
+
+	var line struct {
+		Level      string
+		Operation  string
+		Type       string
+		Variables  map[string]any
+		ErrorCodes []string `json:"error_codes"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
+	}
+	if line.Level != "WARN" || line.Operation != "Login" || line.Type != "mutation" {
+		t.Fatalf("unexpected log line: %s", buf.String())
+	}
+	input, _ := line.Variables["input"].(map[string]any)
+	if line.Variables["token"] != redacted || input["Password"] != redacted || input["name"] != "alice" {
+		t.Fatalf("unexpected variables: %v", line.Variables)
+	}
+	if len(line.ErrorCodes) != 2 || line.ErrorCodes[0] != "FORBIDDEN" || line.ErrorCodes[1] != "INTERNAL" {
+		t.Fatalf("unexpected error codes: %v", line.ErrorCodes)
+	}
+}
@@ -0,0 +1,49 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PgxTracer logs failed PostgreSQL queries, and failures to get a pooled
+// connection for them, with the logger of the request that ran them.
+// Arguments are left out, as they hold user content.
+type PgxTracer struct{}
+
+var (
+	_ pgx.QueryTracer       = PgxTracer{}
+	_ pgxpool.AcquireTracer = PgxTracer{}
+)
+
+type querySQLKey struct{}
+
+func (PgxTracer) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, querySQLKey{}, data.SQL)
+}
+
+func (PgxTracer) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryEndData) {
+	// A cancelled query means the client went away, not a storage failure.
+	if data.Err == nil || errors.Is(data.Err, context.Canceled) {
+		return
+	}
+	sql, _ := ctx.Value(querySQLKey{}).(string)
+	FromContext(ctx).ErrorContext(ctx, "postgres query failed",
+		"sql", strings.Join(strings.Fields(sql), " "),
+		"error", data.Err,
+	)
+}
+
+func (PgxTracer) TraceAcquireStart(ctx context.Context, pool *pgxpool.Pool, data pgxpool.TraceAcquireStartData) context.Context {
+	return ctx
+}
+
+func (PgxTracer) TraceAcquireEnd(ctx context.Context, pool *pgxpool.Pool, data pgxpool.TraceAcquireEndData) {
+	if data.Err == nil || errors.Is(data.Err, context.Canceled) {
+		return
+	}
+	FromContext(ctx).ErrorContext(ctx, "postgres acquire failed", "error", data.Err)
+}
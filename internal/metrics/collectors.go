@@ -0,0 +1,104 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"posts-comments-1/graph/pubsub"
+)
+
+// pubsubCollector reads subscriber counts from the pub/sub at scrape time.
+type pubsubCollector struct {
+	ps pubsub.PubSub
+
+	commentAdded *prometheus.Desc
+	active       *prometheus.Desc
+	lagging      *prometheus.Desc
+	dropped      *prometheus.Desc
+	disconnected *prometheus.Desc
+}
+
+func RegisterPubSub(reg prometheus.Registerer, ps pubsub.PubSub) {
+	reg.MustRegister(&pubsubCollector{
+		ps: ps,
+		commentAdded: prometheus.NewDesc("graphql_comment_added_subscribers",
+			"Active commentAdded subscriptions per post.", []string{"post_id"}, nil),
+		active: prometheus.NewDesc("pubsub_subscribers",
+			"Active subscriptions of all kinds.", nil, nil),
+		lagging: prometheus.NewDesc("pubsub_lagging_subscribers",
+			"Subscribers that overflowed their buffer and have not caught up.", nil, nil),
+		dropped: prometheus.NewDesc("pubsub_dropped_events_total",
+			"Events dropped for slow subscribers.", nil, nil),
+		disconnected: prometheus.NewDesc("pubsub_disconnected_subscribers_total",
+			"Subscribers disconnected for being too slow.", nil, nil),
+	})
+}
+
+func (c *pubsubCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.commentAdded
+	ch <- c.active
+	ch <- c.lagging
+	ch <- c.dropped
+	ch <- c.disconnected
+}
+
+func (c *pubsubCollector) Collect(ch chan<- prometheus.Metric) {
+	for postID, n := range c.ps.Subscribers(pubsub.CommentAdded) {
+		ch <- prometheus.MustNewConstMetric(c.commentAdded, prometheus.GaugeValue, float64(n), postID.String())
+	}
+
+	st := c.ps.Stats()
+	ch <- prometheus.MustNewConstMetric(c.active, prometheus.GaugeValue, float64(st.ActiveSubscribers))
+	ch <- prometheus.MustNewConstMetric(c.lagging, prometheus.GaugeValue, float64(st.LaggingSubscribers))
+	ch <- prometheus.MustNewConstMetric(c.dropped, prometheus.CounterValue, float64(st.DroppedEvents))
+	ch <- prometheus.MustNewConstMetric(c.disconnected, prometheus.CounterValue, float64(st.DisconnectedSubscribers))
+}
+
+// poolCollector reads pgxpool statistics at scrape time.
+type poolCollector struct {
+	pool *pgxpool.Pool
+
+	acquired     *prometheus.Desc
+	idle         *prometheus.Desc
+	total        *prometheus.Desc
+	max          *prometheus.Desc
+	acquires     *prometheus.Desc
+	emptyAcquire *prometheus.Desc
+	waitSeconds  *prometheus.Desc
+}
+
+func RegisterPool(reg prometheus.Registerer, pool *pgxpool.Pool) {
+	reg.MustRegister(&poolCollector{
+		pool:     pool,
+		acquired: prometheus.NewDesc("pgxpool_acquired_conns", "Connections currently in use.", nil, nil),
+		idle:     prometheus.NewDesc("pgxpool_idle_conns", "Idle connections.", nil, nil),
+		total:    prometheus.NewDesc("pgxpool_total_conns", "Open connections.", nil, nil),
+		max:      prometheus.NewDesc("pgxpool_max_conns", "Maximum pool size.", nil, nil),
+		acquires: prometheus.NewDesc("pgxpool_acquires_total", "Successful connection acquires.", nil, nil),
+		emptyAcquire: prometheus.NewDesc("pgxpool_empty_acquires_total",
+			"Acquires that had to wait because the pool was empty.", nil, nil),
+		waitSeconds: prometheus.NewDesc("pgxpool_empty_acquire_wait_seconds_total",
+			"Time spent waiting for a connection in acquires from an empty pool.", nil, nil),
+	})
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.acquired
+	ch <- c.idle
+	ch <- c.total
+	ch <- c.max
+	ch <- c.acquires
+	ch <- c.emptyAcquire
+	ch <- c.waitSeconds
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	st := c.pool.Stat()
+	ch <- prometheus.MustNewConstMetric(c.acquired, prometheus.GaugeValue, float64(st.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(st.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.total, prometheus.GaugeValue, float64(st.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(c.max, prometheus.GaugeValue, float64(st.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquires, prometheus.CounterValue, float64(st.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquire, prometheus.CounterValue, float64(st.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.waitSeconds, prometheus.CounterValue, st.EmptyAcquireWaitTime().Seconds())
+}
@@ -0,0 +1,102 @@
+// Package metrics exposes Prometheus metrics of GraphQL operations, storage
+// calls, subscriptions and the PostgreSQL pool.
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// GraphQL is a gqlgen extension recording the latency and errors of
+// operations and of fields backed by resolvers.
+type GraphQL struct {
+	operationDuration *prometheus.HistogramVec
+	operationErrors   *prometheus.CounterVec
+	fieldDuration     *prometheus.HistogramVec
+	fieldErrors       *prometheus.CounterVec
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.ResponseInterceptor
+	graphql.FieldInterceptor
+} = (*GraphQL)(nil)
+
+func NewGraphQL(reg prometheus.Registerer) *GraphQL {
+	g := &GraphQL{
+		operationDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "graphql_operation_duration_seconds",
+			Help:    "Duration of queries and mutations, from parsing to the response.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"operation", "type"}),
+		operationErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "graphql_operation_errors_total",
+			Help: "Responses with errors; for subscriptions every event counts.",
+		}, []string{"operation", "type"}),
+		fieldDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "graphql_field_duration_seconds",
+			Help:    "Duration of field resolvers.",
+			Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		}, []string{"field"}),
+		fieldErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "graphql_field_errors_total",
+			Help: "Field resolvers that returned an error.",
+		}, []string{"field"}),
+	}
+	reg.MustRegister(g.operationDuration, g.operationErrors, g.fieldDuration, g.fieldErrors)
+	return g
+}
+
+func (*GraphQL) ExtensionName() string {
+	return "Metrics"
+}
+
+func (*GraphQL) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+func (g *GraphQL) InterceptResponse(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	resp := next(ctx)
+	if !graphql.HasOperationContext(ctx) {
+		return resp
+	}
+
+	oc := graphql.GetOperationContext(ctx)
+	name, typ := "anonymous", "unknown"
+	if oc.Operation != nil {
+		typ = string(oc.Operation.Operation)
+		if oc.Operation.Name != "" {
+			name = oc.Operation.Name
+		}
+	}
+
+	// A subscription produces a response per event, whose timing says more
+	// about the event rate than about the server.
+	if typ != string(ast.Subscription) && resp != nil {
+		g.operationDuration.WithLabelValues(name, typ).Observe(time.Since(oc.Stats.OperationStart).Seconds())
+	}
+	if resp != nil && len(resp.Errors) > 0 {
+		g.operationErrors.WithLabelValues(name, typ).Inc()
+	}
+	return resp
+}
+
+func (g *GraphQL) InterceptField(ctx context.Context, next graphql.Resolver) (any, error) {
+	fc := graphql.GetFieldContext(ctx)
+	if fc == nil || !fc.IsResolver {
+		return next(ctx)
+	}
+
+	field := fc.Object + "." + fc.Field.Name
+	start := time.Now()
+	res, err := next(ctx)
+	g.fieldDuration.WithLabelValues(field).Observe(time.Since(start).Seconds())
+	if err != nil {
+		g.fieldErrors.WithLabelValues(field).Inc()
+	}
+	return res, err
+}
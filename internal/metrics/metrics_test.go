@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"posts-comments-1/graph/pubsub"
+	"posts-comments-1/internal/domain"
+	"posts-comments-1/internal/storage/memory"
+)
+
+func TestStorage_CountsCallsAndErrors(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	s := NewStorage(memory.New(), reg)
+	ctx := context.Background()
+
+	if err := s.CreatePost(ctx, domain.Post{Title: "t", Content: "c"}); err != nil {
+		t.Fatalf("CreatePost error: %v", err)
+	}
+	if _, err := s.GetPost(ctx, uuid.New()); !errors.Is(err, domain.ErrPostNotFound) {
+		t.Fatalf("expected ErrPostNotFound, got %v", err)
+	}
+
+	if got := testutil.ToFloat64(s.errors.WithLabelValues("GetPost", string(domain.KindNotFound))); got != 1 {
+		t.Fatalf("expected 1 GetPost error, got %v", got)
+	}
+	if got := testutil.ToFloat64(s.errors.WithLabelValues("CreatePost", string(domain.KindNotFound))); got != 0 {
+		t.Fatalf("expected no CreatePost errors, got %v", got)
+	}
+	if n := testutil.CollectAndCount(s.duration); n != 2 {
+		t.Fatalf("expected histograms for 2 methods, got %d", n)
+	}
+}
+
+func TestPubSubCollector(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ps := pubsub.NewMemory(pubsub.Options{})
+	postID := uuid.New()
+	for range 2 {
+		if _, err := ps.Subscribe(ctx, pubsub.Topic{Kind: pubsub.CommentAdded, PostID: postID}); err != nil {
+			t.Fatalf("Subscribe error: %v", err)
+		}
+	}
+
+	reg := prometheus.NewRegistry()
+	RegisterPubSub(reg, ps)
+
+	want := `
+# HELP graphql_comment_added_subscribers Active commentAdded subscriptions per post.
+# TYPE graphql_comment_added_subscribers gauge
+graphql_comment_added_subscribers{post_id="` + postID.String() + `"} 2
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want), "graphql_comment_added_subscribers"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,214 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"posts-comments-1/internal/domain"
+	"posts-comments-1/internal/storage"
+)
+
+// Storage is a storage.Storage decorator that times every call of the
+// wrapped storage and counts its failures.
+type Storage struct {
+	next     storage.Storage
+	duration *prometheus.HistogramVec
+	errors   *prometheus.CounterVec
+}
+
+var (
+	_ storage.Storage       = (*Storage)(nil)
+	_ storage.HealthChecker = (*Storage)(nil)
+)
+
+func NewStorage(next storage.Storage, reg prometheus.Registerer) *Storage {
+	s := &Storage{
+		next: next,
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "storage_call_duration_seconds",
+			Help:    "Duration of storage calls.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "storage_call_errors_total",
+			Help: "Failed storage calls by error kind, INTERNAL for unexpected errors.",
+		}, []string{"method", "kind"}),
+	}
+	reg.MustRegister(s.duration, s.errors)
+	return s
+}
+
+// track starts timing a call; the returned func records it:
+//
+//	defer s.track("GetPost")(&err)
+func (s *Storage) track(method string) func(*error) {
+	start := time.Now()
+	return func(err *error) {
+		s.duration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+		if *err != nil {
+			kind := string(domain.KindOf(*err))
+			if kind == "" {
+				kind = "INTERNAL"
+			}
+			s.errors.WithLabelValues(method, kind).Inc()
+		}
+	}
+}
+
+// Ping forwards to the wrapped storage when it supports health checks.
+func (s *Storage) Ping(ctx context.Context) (err error) {
+	hc, ok := s.next.(storage.HealthChecker)
+	if !ok {
+		return nil
+	}
+	defer s.track("Ping")(&err)
+	return hc.Ping(ctx)
+}
+
+func (s *Storage) CreatePost(ctx context.Context, post domain.Post) (err error) {
+	defer s.track("CreatePost")(&err)
+	return s.next.CreatePost(ctx, post)
+}
+
+func (s *Storage) GetPost(ctx context.Context, id uuid.UUID) (_ *domain.Post, err error) {
+	defer s.track("GetPost")(&err)
+	return s.next.GetPost(ctx, id)
+}
+
+func (s *Storage) GetPostsByIDs(ctx context.Context, ids []uuid.UUID) (_ []domain.Post, err error) {
+	defer s.track("GetPostsByIDs")(&err)
+	return s.next.GetPostsByIDs(ctx, ids)
+}
+
+func (s *Storage) ListPosts(ctx context.Context, limit, offset int) (_ []domain.Post, err error) {
+	defer s.track("ListPosts")(&err)
+	return s.next.ListPosts(ctx, limit, offset)
+}
+
+func (s *Storage) ListPostsPage(ctx context.Context, p storage.PageParams) (_ *storage.PostPage, err error) {
+	defer s.track("ListPostsPage")(&err)
+	return s.next.ListPostsPage(ctx, p)
+}
+
+func (s *Storage) UpdatePost(ctx context.Context, post domain.Post) (err error) {
+	defer s.track("UpdatePost")(&err)
+	return s.next.UpdatePost(ctx, post)
+}
+
+func (s *Storage) DeletePost(ctx context.Context, id uuid.UUID) (err error) {
+	defer s.track("DeletePost")(&err)
+	return s.next.DeletePost(ctx, id)
+}
+
+func (s *Storage) CreateComment(ctx context.Context, comment domain.Comment) (err error) {
+	defer s.track("CreateComment")(&err)
+	return s.next.CreateComment(ctx, comment)
+}
+
+func (s *Storage) GetComment(ctx context.Context, id uuid.UUID) (_ *domain.Comment, err error) {
+	defer s.track("GetComment")(&err)
+	return s.next.GetComment(ctx, id)
+}
+
+func (s *Storage) GetCommentsByIDs(ctx context.Context, ids []uuid.UUID) (_ []domain.Comment, err error) {
+	defer s.track("GetCommentsByIDs")(&err)
+	return s.next.GetCommentsByIDs(ctx, ids)
+}
+
+func (s *Storage) GetComments(ctx context.Context, postID uuid.UUID, limit, offset int, includeHidden bool) (_ []domain.Comment, err error) {
+	defer s.track("GetComments")(&err)
+	return s.next.GetComments(ctx, postID, limit, offset, includeHidden)
+}
+
+func (s *Storage) GetCommentsPage(ctx context.Context, postID uuid.UUID, p storage.PageParams, includeHidden bool) (_ *storage.CommentPage, err error) {
+	defer s.track("GetCommentsPage")(&err)
+	return s.next.GetCommentsPage(ctx, postID, p, includeHidden)
+}
+
+func (s *Storage) GetReplies(ctx context.Context, parentID uuid.UUID, p storage.PageParams, includeHidden bool) (_ *storage.CommentPage, err error) {
+	defer s.track("GetReplies")(&err)
+	return s.next.GetReplies(ctx, parentID, p, includeHidden)
+}
+
+func (s *Storage) CountReplies(ctx context.Context, commentID uuid.UUID, includeHidden bool) (_ int, err error) {
+	defer s.track("CountReplies")(&err)
+	return s.next.CountReplies(ctx, commentID, includeHidden)
+}
+
+func (s *Storage) CountRepliesByCommentIDs(ctx context.Context, ids []uuid.UUID, includeHidden bool) (_ map[uuid.UUID]int, err error) {
+	defer s.track("CountRepliesByCommentIDs")(&err)
+	return s.next.CountRepliesByCommentIDs(ctx, ids, includeHidden)
+}
+
+func (s *Storage) CountCommentsByPostIDs(ctx context.Context, ids []uuid.UUID, includeHidden bool) (_ map[uuid.UUID]int, err error) {
+	defer s.track("CountCommentsByPostIDs")(&err)
+	return s.next.CountCommentsByPostIDs(ctx, ids, includeHidden)
+}
+
+func (s *Storage) GetCommentTree(ctx context.Context, postID uuid.UUID, maxDepth, perNode int, includeHidden bool) (_ []domain.Comment, err error) {
+	defer s.track("GetCommentTree")(&err)
+	return s.next.GetCommentTree(ctx, postID, maxDepth, perNode, includeHidden)
+}
+
+func (s *Storage) FlagComment(ctx context.Context, flag domain.CommentFlag) (err error) {
+	defer s.track("FlagComment")(&err)
+	return s.next.FlagComment(ctx, flag)
+}
+
+func (s *Storage) GetOpenFlags(ctx context.Context, p storage.PageParams) (_ *storage.FlagPage, err error) {
+	defer s.track("GetOpenFlags")(&err)
+	return s.next.GetOpenFlags(ctx, p)
+}
+
+func (s *Storage) ResolveFlag(ctx context.Context, flagID, resolvedBy uuid.UUID, status domain.ModerationStatus) (_ *domain.Comment, err error) {
+	defer s.track("ResolveFlag")(&err)
+	return s.next.ResolveFlag(ctx, flagID, resolvedBy, status)
+}
+
+func (s *Storage) HasRecentComment(ctx context.Context, authorID uuid.UUID, content string, since time.Time) (_ bool, err error) {
+	defer s.track("HasRecentComment")(&err)
+	return s.next.HasRecentComment(ctx, authorID, content, since)
+}
+
+func (s *Storage) RecordFilterDecisions(ctx context.Context, decisions []domain.FilterDecision) (err error) {
+	defer s.track("RecordFilterDecisions")(&err)
+	return s.next.RecordFilterDecisions(ctx, decisions)
+}
+
+func (s *Storage) UpdateComment(ctx context.Context, comment domain.Comment, editorID uuid.UUID) (_ *domain.Comment, err error) {
+	defer s.track("UpdateComment")(&err)
+	return s.next.UpdateComment(ctx, comment, editorID)
+}
+
+func (s *Storage) GetCommentRevisions(ctx context.Context, commentID uuid.UUID, p storage.PageParams) (_ *storage.RevisionPage, err error) {
+	defer s.track("GetCommentRevisions")(&err)
+	return s.next.GetCommentRevisions(ctx, commentID, p)
+}
+
+func (s *Storage) DeleteComment(ctx context.Context, id, deletedBy uuid.UUID) (err error) {
+	defer s.track("DeleteComment")(&err)
+	return s.next.DeleteComment(ctx, id, deletedBy)
+}
+
+func (s *Storage) PurgeComment(ctx context.Context, id uuid.UUID) (_ []uuid.UUID, err error) {
+	defer s.track("PurgeComment")(&err)
+	return s.next.PurgeComment(ctx, id)
+}
+
+func (s *Storage) UpsertUser(ctx context.Context, user domain.User) (err error) {
+	defer s.track("UpsertUser")(&err)
+	return s.next.UpsertUser(ctx, user)
+}
+
+func (s *Storage) GetUser(ctx context.Context, id uuid.UUID) (_ *domain.User, err error) {
+	defer s.track("GetUser")(&err)
+	return s.next.GetUser(ctx, id)
+}
+
+func (s *Storage) GetUsersByIDs(ctx context.Context, ids []uuid.UUID) (_ []domain.User, err error) {
+	defer s.track("GetUsersByIDs")(&err)
+	return s.next.GetUsersByIDs(ctx, ids)
+}
@@ -0,0 +1,64 @@
+package migrate
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"posts-comments-1/migrations"
+)
+
+func TestLoad_Embedded(t *testing.T) {
+	got, err := Load(migrations.FS)
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+	if len(got) == 0 {
+		t.Fatal("expected migrations")
+	}
+	for i, m := range got {
+		if m.Version != int64(i+1) {
+			t.Fatalf("expected version %d at %d, got %d", i+1, i, m.Version)
+		}
+	}
+}
+
+func TestLoad_Ordered(t *testing.T) {
+	fsys := fstest.MapFS{
+		"010_b.up.sql":   {Data: []byte("B")},
+		"010_b.down.sql": {Data: []byte("-B")},
+		"002_a.up.sql":   {Data: []byte("A")},
+		"002_a.down.sql": {Data: []byte("-A")},
+		"README.md":      {Data: []byte("ignored")},
+	}
+
+	got, err := Load(fsys)
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+	if len(got) != 2 || got[0].Version != 2 || got[1].Version != 10 {
+		t.Fatalf("unexpected migrations: %+v", got)
+	}
+	if got[0].Name != "a" || got[0].Up != "A" || got[0].Down != "-A" {
+		t.Fatalf("unexpected migration: %+v", got[0])
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	cases := map[string]fstest.MapFS{
+		"missing down": {
+			"001_a.up.sql": {Data: []byte("A")},
+		},
+		"bad name": {
+			"init.sql": {Data: []byte("A")},
+		},
+		"two names": {
+			"001_a.up.sql":   {Data: []byte("A")},
+			"001_b.down.sql": {Data: []byte("-B")},
+		},
+	}
+	for name, fsys := range cases {
+		if _, err := Load(fsys); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package persisted
+
+import (
+	"context"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// Error codes of operations rejected by Allowlist.
+const (
+	// CodeNotFound is returned for a hash missing from the manifest. It is
+	// the code of automatic persisted queries, so clients using them fall
+	// back to sending the query, which is then checked against the
+	// manifest as well.
+	CodeNotFound   = "PERSISTED_QUERY_NOT_FOUND"
+	CodeNotAllowed = "OPERATION_NOT_ALLOWED"
+)
+
+func init() {
+	errcode.RegisterErrorType(CodeNotAllowed, errcode.KindProtocol)
+}
+
+// Allowlist is a gqlgen extension that only runs operations from its
+// manifest. Clients send the hash in the persistedQuery extension, as with
+// automatic persisted queries; a query sent as text is accepted when it
+// is identical to a registered one. It replaces
+// extension.AutomaticPersistedQuery, which would register any query.
+type Allowlist struct {
+	Manifest Manifest
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationParameterMutator
+} = Allowlist{}
+
+func (Allowlist) ExtensionName() string {
+	return "PersistedQueryAllowlist"
+}
+
+func (Allowlist) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+func (a Allowlist) MutateOperationParameters(ctx context.Context, p *graphql.RawParams) *gqlerror.Error {
+	var hash string
+	if ext, ok := p.Extensions["persistedQuery"].(map[string]any); ok {
+		hash, _ = ext["sha256Hash"].(string)
+	}
+
+	if hash == "" {
+		if _, ok := a.Manifest[Hash(p.Query)]; !ok {
+			return codeError(CodeNotAllowed, "only operations registered in the persisted query manifest are allowed")
+		}
+		return nil
+	}
+
+	query, ok := a.Manifest[hash]
+	if !ok {
+		return codeError(CodeNotFound, "PersistedQueryNotFound")
+	}
+	if p.Query != "" && p.Query != query {
+		return codeError(CodeNotAllowed, "query does not match the persisted query hash")
+	}
+	p.Query = query
+	return nil
+}
+
+func codeError(code, message string) *gqlerror.Error {
+	err := gqlerror.Errorf("%s", message)
+	errcode.Set(err, code)
+	return err
+}
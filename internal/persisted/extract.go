@@ -0,0 +1,92 @@
+package persisted
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/formatter"
+	"github.com/vektah/gqlparser/v2/parser"
+	"github.com/vektah/gqlparser/v2/validator"
+)
+
+// Operation is an operation extracted from client sources.
+type Operation struct {
+	Name string
+	Type ast.Operation
+	Hash string
+	// Document holds the operation and the fragments it uses, formatted
+	// canonically.
+	Document string
+}
+
+// Extract validates the operations and fragments of sources against
+// schema and returns every operation as a standalone document. Fragments
+// may be defined in any of the sources. Operations must be named, so the
+// client can tell which hash to send.
+func Extract(schema *ast.Schema, sources ...*ast.Source) ([]Operation, error) {
+	doc := &ast.QueryDocument{}
+	for _, src := range sources {
+		d, err := parser.ParseQuery(src)
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: %w", src.Name, err)
+		}
+		doc.Operations = append(doc.Operations, d.Operations...)
+		doc.Fragments = append(doc.Fragments, d.Fragments...)
+	}
+	for _, op := range doc.Operations {
+		if op.Name == "" {
+			return nil, fmt.Errorf("%s:%d: operations must be named", op.Position.Src.Name, op.Position.Line)
+		}
+	}
+	if errs := validator.Validate(schema, doc); len(errs) > 0 {
+		return nil, errs
+	}
+
+	ops := make([]Operation, 0, len(doc.Operations))
+	for _, op := range doc.Operations {
+		single := &ast.QueryDocument{Operations: ast.OperationList{op}}
+		collectFragments(doc, op.SelectionSet, map[string]bool{}, &single.Fragments)
+
+		var buf bytes.Buffer
+		formatter.NewFormatter(&buf, formatter.WithIndent("  ")).FormatQueryDocument(single)
+		ops = append(ops, Operation{
+			Name:     op.Name,
+			Type:     op.Operation,
+			Hash:     Hash(buf.String()),
+			Document: buf.String(),
+		})
+	}
+	return ops, nil
+}
+
+// NewManifest returns the manifest allowing ops.
+func NewManifest(ops []Operation) Manifest {
+	m := make(Manifest, len(ops))
+	for _, op := range ops {
+		m[op.Hash] = op.Document
+	}
+	return m
+}
+
+// collectFragments appends the fragments used by set, directly or through
+// other fragments, to out.
+func collectFragments(doc *ast.QueryDocument, set ast.SelectionSet, seen map[string]bool, out *ast.FragmentDefinitionList) {
+	for _, sel := range set {
+		switch sel := sel.(type) {
+		case *ast.Field:
+			collectFragments(doc, sel.SelectionSet, seen, out)
+		case *ast.InlineFragment:
+			collectFragments(doc, sel.SelectionSet, seen, out)
+		case *ast.FragmentSpread:
+			if seen[sel.Name] {
+				continue
+			}
+			seen[sel.Name] = true
+			if f := doc.Fragments.ForName(sel.Name); f != nil {
+				*out = append(*out, f)
+				collectFragments(doc, f.SelectionSet, seen, out)
+			}
+		}
+	}
+}
@@ -0,0 +1,52 @@
+// Package persisted restricts the server to operations registered ahead of
+// time in a manifest, and builds such manifests from client sources.
+package persisted
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Manifest maps the hash of every allowed query document to the document.
+type Manifest map[string]string
+
+// Hash returns the hex-encoded SHA-256 of query, the hash clients send in
+// the persistedQuery extension of automatic persisted queries.
+func Hash(query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(sum[:])
+}
+
+// LoadManifest reads a manifest written by Manifest.Write and checks that
+// every hash matches its document.
+func LoadManifest(path string) (Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read manifest: %w", err)
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parse manifest %s: %w", path, err)
+	}
+	for hash, query := range m {
+		if Hash(query) != hash {
+			return nil, fmt.Errorf("manifest %s: hash %s does not match its query", path, hash)
+		}
+	}
+	return m, nil
+}
+
+// Write writes m as a JSON object sorted by hash.
+func (m Manifest) Write(w io.Writer) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
@@ -0,0 +1,128 @@
+package persisted
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+var testSchema = gqlparser.MustLoadSchema(&ast.Source{Input: `
+	type Query { post(id: ID!): Post }
+	type Post { id: ID! title: String! author: User! }
+	type User { id: ID! name: String! }
+`})
+
+func TestExtract_IncludesUsedFragments(t *testing.T) {
+	ops, err := Extract(testSchema,
+		&ast.Source{Name: "post.graphql", Input: `
+			query Post($id: ID!) { post(id: $id) { ...PostFields } }
+			query Title($id: ID!) { post(id: $id) { title } }
+		`},
+		&ast.Source{Name: "fragments.graphql", Input: `
+			fragment PostFields on Post { id title author { ...UserFields } }
+			fragment UserFields on User { id name }
+		`},
+	)
+	if err != nil {
+		t.Fatalf("Extract error: %v", err)
+	}
+	if len(ops) != 2 {
+		t.Fatalf("expected 2 operations, got %d", len(ops))
+	}
+
+	post, title := ops[0], ops[1]
+	if post.Name != "Post" || post.Type != ast.Query || post.Hash != Hash(post.Document) {
+		t.Fatalf("unexpected operation %+v", post)
+	}
+	for _, f := range []string{"fragment PostFields", "fragment UserFields"} {
+		if !strings.Contains(post.Document, f) {
+			t.Fatalf("expected %q in the document:\n%s", f, post.Document)
+		}
+	}
+	if strings.Contains(title.Document, "fragment") {
+		t.Fatalf("expected no fragments in the document:\n%s", title.Document)
+	}
+}
+
+func TestExtract_RejectsInvalidOperations(t *testing.T) {
+	for name, input := range map[string]string{
+		"anonymous":     `{ post(id: "1") { id } }`,
+		"unknown field": `query Q { post(id: "1") { body } }`,
+	} {
+		if _, err := Extract(testSchema, &ast.Source{Name: "q.graphql", Input: input}); err == nil {
+			t.Fatalf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestLoadManifest_ChecksHashes(t *testing.T) {
+	dir := t.TempDir()
+	query := `query Q { post(id: "1") { id } }`
+
+	path := filepath.Join(dir, "ok.json")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Create error: %v", err)
+	}
+	if err := (Manifest{Hash(query): query}).Write(f); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	f.Close()
+	m, err := LoadManifest(path)
+	if err != nil {
+		t.Fatalf("LoadManifest error: %v", err)
+	}
+	if m[Hash(query)] != query {
+		t.Fatalf("unexpected manifest %v", m)
+	}
+
+	path = filepath.Join(dir, "bad.json")
+	if err := os.WriteFile(path, []byte(`{"abc": "query Q { post(id: \"1\") { id } }"}`), 0o644); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+	if _, err := LoadManifest(path); err == nil {
+		t.Fatal("expected an error for a mismatched hash")
+	}
+}
+
+func TestAllowlist_MutateOperationParameters(t *testing.T) {
+	query := `query Q { post(id: "1") { id } }`
+	a := Allowlist{Manifest: Manifest{Hash(query): query}}
+	withHash := func(hash string) map[string]any {
+		return map[string]any{"persistedQuery": map[string]any{"version": 1, "sha256Hash": hash}}
+	}
+
+	tests := []struct {
+		name   string
+		params graphql.RawParams
+		code   string
+	}{
+		{name: "registered hash", params: graphql.RawParams{Extensions: withHash(Hash(query))}},
+		{name: "registered query", params: graphql.RawParams{Query: query}},
+		{name: "unknown hash", params: graphql.RawParams{Extensions: withHash(Hash("{ x }"))}, code: CodeNotFound},
+		{name: "unknown query", params: graphql.RawParams{Query: `{ post(id: "1") { title } }`}, code: CodeNotAllowed},
+		{name: "mismatched query", params: graphql.RawParams{Query: "{ x }", Extensions: withHash(Hash(query))}, code: CodeNotAllowed},
+	}
+	for _, tt := range tests {
+		p := tt.params
+		err := a.MutateOperationParameters(context.Background(), &p)
+		if tt.code == "" {
+			if err != nil {
+				t.Fatalf("%s: MutateOperationParameters error: %v", tt.name, err)
+			}
+			if p.Query != query {
+				t.Fatalf("%s: expected the registered query, got %q", tt.name, p.Query)
+			}
+			continue
+		}
+		if err == nil || err.Extensions["code"] != tt.code {
+			t.Fatalf("%s: expected %s, got %v", tt.name, tt.code, err)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+
+	"posts-comments-1/internal/auth"
+	"posts-comments-1/internal/logging"
+)
+
+// CodeRateLimited is the extensions.code of mutations refused by Limiter.
+// extensions.retryAfter holds the seconds until the next attempt can
+// succeed.
+const CodeRateLimited = "RATE_LIMITED"
+
+// Limiter is a gqlgen extension that takes a token for every mutation
+// field, so aliased mutations in one operation count one by one. Buckets
+// are kept per mutation and caller: the authenticated user, or the client
+// IP for anonymous requests.
+type Limiter struct {
+	Store Store
+	// Limits by mutation name; "*" applies to mutations not listed.
+	// Mutations without a limit are not counted.
+	Limits map[string]Limit
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.FieldInterceptor
+} = (*Limiter)(nil)
+
+func (*Limiter) ExtensionName() string {
+	return "RateLimit"
+}
+
+func (*Limiter) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+func (l *Limiter) InterceptField(ctx context.Context, next graphql.Resolver) (any, error) {
+	fc := graphql.GetFieldContext(ctx)
+	if fc == nil || fc.Object != "Mutation" || !fc.IsResolver {
+		return next(ctx)
+	}
+
+	name := fc.Field.Name
+	limit, ok := l.Limits[name]
+	if !ok {
+		if limit, ok = l.Limits["*"]; !ok {
+			return next(ctx)
+		}
+	}
+
+	res, err := l.Store.Take(ctx, name+":"+caller(ctx), limit)
+	if err != nil {
+		// An unavailable store must not take mutations down with it.
+		logging.FromContext(ctx).ErrorContext(ctx, "rate limit check failed, allowing", "mutation", name, "error", err)
+		return next(ctx)
+	}
+	if !res.Allowed {
+		retryAfter := int(math.Ceil(res.RetryAfter.Seconds()))
+		return nil, &gqlerror.Error{
+			Message: fmt.Sprintf("too many %s requests, retry in %d s", name, retryAfter),
+			Extensions: map[string]any{
+				"code":       CodeRateLimited,
+				"retryAfter": retryAfter,
+			},
+		}
+	}
+	return next(ctx)
+}
+
+// caller identifies whose bucket a request draws from.
+func caller(ctx context.Context) string {
+	if u, ok := auth.UserFromContext(ctx); ok {
+		return "user:" + u.ID.String()
+	}
+	return "ip:" + ClientIP(ctx)
+}
@@ -0,0 +1,36 @@
+package ratelimit
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+)
+
+type clientIPKey struct{}
+
+// ClientIP returns the IP of the client the request ctx belongs to.
+func ClientIP(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey{}).(string)
+	return ip
+}
+
+// Middleware puts the client IP into the request context. With
+// trustForwardedFor it is taken from the first X-Forwarded-For entry, which
+// clients can forge unless a proxy in front of the server overwrites it.
+func Middleware(trustForwardedFor bool, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := ""
+		if trustForwardedFor {
+			first, _, _ := strings.Cut(r.Header.Get("X-Forwarded-For"), ",")
+			ip = strings.TrimSpace(first)
+		}
+		if ip == "" {
+			ip = r.RemoteAddr
+			if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+				ip = host
+			}
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientIPKey{}, ip)))
+	})
+}
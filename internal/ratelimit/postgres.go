@@ -0,0 +1,86 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"posts-comments-1/internal/logging"
+)
+
+// Postgres is a Store shared by all replicas connected to the same
+// database. Buckets live in the rate_limit_buckets table and are timed by
+// the database clock.
+type Postgres struct {
+	pool *pgxpool.Pool
+
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+var _ Store = (*Postgres)(nil)
+
+func NewPostgres(pool *pgxpool.Pool) *Postgres {
+	return &Postgres{pool: pool}
+}
+
+func (p *Postgres) Take(ctx context.Context, key string, l Limit) (Result, error) {
+	var res Result
+	err := pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		// Creates a full bucket on first use; otherwise the no-op update
+		// locks the row until the transaction ends.
+		const qLock = `
+INSERT INTO rate_limit_buckets (key, tokens, updated_at, full_at)
+VALUES ($1, $2, now(), now())
+ON CONFLICT (key) DO UPDATE SET key = EXCLUDED.key
+RETURNING tokens, EXTRACT(EPOCH FROM now() - updated_at)::float8;
+`
+		var tokens, elapsed float64
+		if err := tx.QueryRow(ctx, qLock, key, float64(l.Burst)).Scan(&tokens, &elapsed); err != nil {
+			return fmt.Errorf("lock bucket: %w", err)
+		}
+
+		var left float64
+		var untilFull time.Duration
+		res, left, untilFull = take(tokens, seconds(elapsed), l)
+
+		const qUpdate = `
+UPDATE rate_limit_buckets
+SET tokens = $2,
+    updated_at = now(),
+    full_at = now() + make_interval(secs => $3)
+WHERE key = $1;
+`
+		if _, err := tx.Exec(ctx, qUpdate, key, left, untilFull.Seconds()); err != nil {
+			return fmt.Errorf("update bucket: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return Result{}, fmt.Errorf("take rate limit token: %w", err)
+	}
+
+	if p.sweepDue() {
+		const qSweep = `DELETE FROM rate_limit_buckets WHERE full_at < now();`
+		if _, err := p.pool.Exec(ctx, qSweep); err != nil {
+			logging.FromContext(ctx).WarnContext(ctx, "sweep rate limit buckets failed", "error", err)
+		}
+	}
+	return res, nil
+}
+
+// sweepDue reports whether this replica should drop full buckets now.
+func (p *Postgres) sweepDue() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if time.Since(p.lastSweep) < sweepInterval {
+		return false
+	}
+	p.lastSweep = time.Now()
+	return true
+}
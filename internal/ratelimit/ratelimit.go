@@ -0,0 +1,54 @@
+// Package ratelimit limits how often callers may run mutations, with token
+// buckets kept per caller and mutation in memory or in PostgreSQL.
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Limit allows Burst requests at once, refilled evenly over Period.
+type Limit struct {
+	Burst  int
+	Period time.Duration
+}
+
+// perSecond is the refill rate of the bucket.
+func (l Limit) perSecond() float64 {
+	return float64(l.Burst) / l.Period.Seconds()
+}
+
+// Result is the outcome of taking a token.
+type Result struct {
+	Allowed bool
+	// RetryAfter is how long until a token is available when not allowed.
+	RetryAfter time.Duration
+}
+
+// Store keeps token buckets by key.
+type Store interface {
+	// Take takes a token from the bucket of key, created full on first
+	// use.
+	Take(ctx context.Context, key string, l Limit) (Result, error)
+}
+
+// take refills a bucket holding tokens for elapsed and takes a token from
+// it. It returns the result, the tokens left and how long until the
+// bucket is full again.
+func take(tokens float64, elapsed time.Duration, l Limit) (Result, float64, time.Duration) {
+	rate := l.perSecond()
+	tokens = min(float64(l.Burst), tokens+max(elapsed.Seconds(), 0)*rate)
+
+	var res Result
+	if tokens >= 1 {
+		tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = seconds((1 - tokens) / rate)
+	}
+	return res, tokens, seconds((float64(l.Burst) - tokens) / rate)
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
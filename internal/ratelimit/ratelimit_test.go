@@ -0,0 +1,85 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/google/uuid"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+
+	"posts-comments-1/internal/auth"
+	"posts-comments-1/internal/domain"
+)
+
+func TestMemory_TokenBucket(t *testing.T) {
+	now := time.Unix(0, 0)
+	m := NewMemory()
+	m.now = func() time.Time { return now }
+	l := Limit{Burst: 2, Period: 10 * time.Second}
+
+	for i := range 2 {
+		if res, _ := m.Take(context.Background(), "k", l); !res.Allowed {
+			t.Fatalf("take %d: expected the burst to be allowed", i)
+		}
+	}
+	res, _ := m.Take(context.Background(), "k", l)
+	if res.Allowed || res.RetryAfter != 5*time.Second {
+		t.Fatalf("expected a refusal with a 5s retry, got %+v", res)
+	}
+	if res, _ := m.Take(context.Background(), "other", l); !res.Allowed {
+		t.Fatal("expected buckets to be independent")
+	}
+
+	now = now.Add(5 * time.Second)
+	if res, _ := m.Take(context.Background(), "k", l); !res.Allowed {
+		t.Fatal("expected a token after the refill")
+	}
+
+	// Full buckets are dropped by the sweep.
+	now = now.Add(time.Hour)
+	_, _ = m.Take(context.Background(), "k", l)
+	if _, ok := m.buckets["other"]; ok {
+		t.Fatal("expected the full bucket to be swept")
+	}
+}
+
+func mutation(ctx context.Context, name string) context.Context {
+	return graphql.WithFieldContext(ctx, &graphql.FieldContext{
+		Object:     "Mutation",
+		Field:      graphql.CollectedField{Field: &ast.Field{Name: name}},
+		IsResolver: true,
+	})
+}
+
+func TestLimiter_PerCallerAndMutation(t *testing.T) {
+	l := &Limiter{
+		Store: NewMemory(),
+		Limits: map[string]Limit{
+			"createComment": {Burst: 1, Period: time.Minute},
+			"*":             {Burst: 5, Period: time.Minute},
+		},
+	}
+	next := func(context.Context) (any, error) { return "ok", nil }
+	alice := auth.WithUser(context.Background(), domain.User{ID: uuid.New(), Name: "alice"})
+
+	if _, err := l.InterceptField(mutation(alice, "createComment"), next); err != nil {
+		t.Fatalf("InterceptField error: %v", err)
+	}
+	_, err := l.InterceptField(mutation(alice, "createComment"), next)
+	var gqlErr *gqlerror.Error
+	if !errors.As(err, &gqlErr) || gqlErr.Extensions["code"] != CodeRateLimited || gqlErr.Extensions["retryAfter"] != 60 {
+		t.Fatalf("expected %s with retryAfter 60, got %v", CodeRateLimited, err)
+	}
+
+	if _, err := l.InterceptField(mutation(alice, "createPost"), next); err != nil {
+		t.Fatalf("expected other mutations to use their own bucket, got %v", err)
+	}
+	bob := auth.WithUser(context.Background(), domain.User{ID: uuid.New(), Name: "bob"})
+	if _, err := l.InterceptField(mutation(bob, "createComment"), next); err != nil {
+		t.Fatalf("expected other callers to use their own bucket, got %v", err)
+	}
+}
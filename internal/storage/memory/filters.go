@@ -0,0 +1,55 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"posts-comments-1/internal/domain"
+)
+
+func (m *MemoryStorage) HasRecentComment(ctx context.Context, authorID uuid.UUID, content string, since time.Time) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ids := m.commentsByAuthor[authorID]
+	start := sort.Search(len(ids), func(i int) bool {
+		return !m.commentsByID[ids[i]].CreatedAt.Before(since)
+	})
+
+	content = strings.TrimSpace(content)
+	for _, id := range ids[start:] {
+		c := m.commentsByID[id]
+		if !c.Deleted() && strings.EqualFold(strings.TrimSpace(c.Content), content) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *MemoryStorage) RecordFilterDecisions(ctx context.Context, decisions []domain.FilterDecision) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, d := range decisions {
+		if d.ID == uuid.Nil {
+			d.ID = uuid.New()
+		}
+		if d.CreatedAt.IsZero() {
+			d.CreatedAt = time.Now()
+		}
+		m.filterDecisions = append(m.filterDecisions, d)
+	}
+	return nil
+}
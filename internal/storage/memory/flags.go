@@ -0,0 +1,114 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"posts-comments-1/internal/domain"
+	"posts-comments-1/internal/storage"
+)
+
+func (m *MemoryStorage) FlagComment(ctx context.Context, f domain.CommentFlag) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, ok := m.commentsByID[f.CommentID]
+	if !ok {
+		return domain.ErrCommentNotFound
+	}
+	if c.Deleted() {
+		return domain.ErrCommentDeleted
+	}
+
+	if f.ID == uuid.Nil {
+		f.ID = uuid.New()
+	}
+	if f.CreatedAt.IsZero() {
+		f.CreatedAt = time.Now()
+	}
+	f.ResolvedAt, f.ResolvedBy, f.Resolution = nil, uuid.Nil, ""
+	m.flags[f.ID] = f
+
+	if c.Status == domain.StatusVisible {
+		c.Status = domain.StatusPending
+		m.commentsByID[c.ID] = c
+	}
+	return nil
+}
+
+func (m *MemoryStorage) GetOpenFlags(ctx context.Context, p storage.PageParams) (*storage.FlagPage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var open []domain.CommentFlag
+	for _, f := range m.flags {
+		if !f.Resolved() {
+			open = append(open, f)
+		}
+	}
+	window, hasNext, hasPrev := paginate(open, storage.FlagCursor, p)
+	return &storage.FlagPage{
+		Flags:           window,
+		HasNextPage:     hasNext,
+		HasPreviousPage: hasPrev,
+		TotalCount:      len(open),
+	}, nil
+}
+
+func (m *MemoryStorage) ResolveFlag(ctx context.Context, flagID, resolvedBy uuid.UUID, status domain.ModerationStatus) (*domain.Comment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if !domain.ValidResolution(status) {
+		return nil, domain.ErrInvalidResolution
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	flag, ok := m.flags[flagID]
+	if !ok {
+		return nil, domain.ErrFlagNotFound
+	}
+	if flag.Resolved() {
+		return nil, domain.ErrFlagResolved
+	}
+	c, ok := m.commentsByID[flag.CommentID]
+	if !ok {
+		return nil, domain.ErrCommentNotFound
+	}
+
+	now := time.Now()
+	for id, f := range m.flags {
+		if f.CommentID == c.ID && !f.Resolved() {
+			f.ResolvedAt, f.ResolvedBy, f.Resolution = &now, resolvedBy, status
+			m.flags[id] = f
+		}
+	}
+	c.Status = status
+	m.commentsByID[c.ID] = c
+	return &c, nil
+}
+
+// dropFlagsLocked removes the flags of removed comments. m.mu must be held.
+func (m *MemoryStorage) dropFlagsLocked(commentIDs []uuid.UUID) {
+	removed := make(map[uuid.UUID]bool, len(commentIDs))
+	for _, id := range commentIDs {
+		removed[id] = true
+	}
+	for id, f := range m.flags {
+		if removed[f.CommentID] {
+			delete(m.flags, id)
+		}
+	}
+}
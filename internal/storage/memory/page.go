@@ -0,0 +1,48 @@
+package memory
+
+import (
+	"sort"
+
+	"posts-comments-1/internal/storage"
+)
+
+// paginate applies keyset pagination to items. It returns the selected
+// window in ascending order along with the next/previous page flags.
+func paginate[T any](items []T, cursor func(T) storage.Cursor, p storage.PageParams) ([]T, bool, bool) {
+	sorted := make([]T, len(items))
+	copy(sorted, items)
+	sort.Slice(sorted, func(i, j int) bool {
+		return cursor(sorted[i]).Less(cursor(sorted[j]))
+	})
+
+	window := make([]T, 0, len(sorted))
+	for _, it := range sorted {
+		c := cursor(it)
+		if p.After != nil && !p.After.Less(c) {
+			continue
+		}
+		if p.Before != nil && !c.Less(*p.Before) {
+			continue
+		}
+		window = append(window, it)
+	}
+
+	limit := p.Limit()
+	if limit <= 0 {
+		return []T{}, false, false
+	}
+
+	if p.Backward() {
+		hasPrev := len(window) > limit
+		if hasPrev {
+			window = window[len(window)-limit:]
+		}
+		return window, p.Before != nil, hasPrev
+	}
+
+	hasNext := len(window) > limit
+	if hasNext {
+		window = window[:limit]
+	}
+	return window, hasNext, p.After != nil
+}
@@ -1,8 +1,10 @@
 package memory
 
 import (
-	"errors"
+	"context"
 	"posts-comments-1/internal/domain"
+	"posts-comments-1/internal/storage"
+	"slices"
 	"sort"
 	"sync"
 	"time"
@@ -10,31 +12,42 @@ import (
 	"github.com/google/uuid"
 )
 
-var (
-	ErrPostNotFound           = errors.New("post not found")
-	ErrParentCommentWrongPost = errors.New("parent comment belongs to another post")
-	ErrCommentNotFound        = errors.New("comment not found")
-	ErrCommentTooLong         = errors.New("comment content exceeds maximum length")
-	ErrCommentsDisabled       = errors.New("comments aredisabled")
-)
-
 type MemoryStorage struct {
+	users          map[uuid.UUID]domain.User
 	posts          map[uuid.UUID]domain.Post
 	commentsByID   map[uuid.UUID]domain.Comment
 	commentsByPost map[uuid.UUID][]uuid.UUID
+	// repliesByParent indexes direct replies by their parent comment ID.
+	repliesByParent map[uuid.UUID][]uuid.UUID
+	// commentsByAuthor indexes comments by author, ordered by CreatedAt.
+	commentsByAuthor map[uuid.UUID][]uuid.UUID
+	// revisions holds the revisions of every edited comment, oldest first.
+	revisions map[uuid.UUID][]domain.CommentRevision
+	flags     map[uuid.UUID]domain.CommentFlag
+	// filterDecisions is the audit log of content filters.
+	filterDecisions []domain.FilterDecision
 
 	mu sync.RWMutex
 }
 
 func New() *MemoryStorage {
 	return &MemoryStorage{
-		posts:          make(map[uuid.UUID]domain.Post),
-		commentsByID:   make(map[uuid.UUID]domain.Comment),
-		commentsByPost: make(map[uuid.UUID][]uuid.UUID),
+		users:            make(map[uuid.UUID]domain.User),
+		posts:            make(map[uuid.UUID]domain.Post),
+		commentsByID:     make(map[uuid.UUID]domain.Comment),
+		commentsByPost:   make(map[uuid.UUID][]uuid.UUID),
+		repliesByParent:  make(map[uuid.UUID][]uuid.UUID),
+		commentsByAuthor: make(map[uuid.UUID][]uuid.UUID),
+		revisions:        make(map[uuid.UUID][]domain.CommentRevision),
+		flags:            make(map[uuid.UUID]domain.CommentFlag),
 	}
 }
 
-func (m *MemoryStorage) CreatePost(post domain.Post) error {
+func (m *MemoryStorage) CreatePost(ctx context.Context, post domain.Post) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -49,18 +62,43 @@ func (m *MemoryStorage) CreatePost(post domain.Post) error {
 	return nil
 }
 
-func (m *MemoryStorage) GetPost(id uuid.UUID) (*domain.Post, error) {
+func (m *MemoryStorage) GetPost(ctx context.Context, id uuid.UUID) (*domain.Post, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
 	post, ok := m.posts[id]
 	if !ok {
-		return nil, ErrPostNotFound
+		return nil, domain.ErrPostNotFound
 	}
 	return &post, nil
 }
 
-func (m *MemoryStorage) ListPosts(limit, offset int) ([]domain.Post, error) {
+func (m *MemoryStorage) GetPostsByIDs(ctx context.Context, ids []uuid.UUID) ([]domain.Post, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	posts := make([]domain.Post, 0, len(ids))
+	for _, id := range ids {
+		if p, ok := m.posts[id]; ok {
+			posts = append(posts, p)
+		}
+	}
+	return posts, nil
+}
+
+func (m *MemoryStorage) ListPosts(ctx context.Context, limit, offset int) ([]domain.Post, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
@@ -93,58 +131,99 @@ func (m *MemoryStorage) ListPosts(limit, offset int) ([]domain.Post, error) {
 	return posts[offset:end], nil
 }
 
-func (m *MemoryStorage) UpdatePost(post domain.Post) error {
+func (m *MemoryStorage) ListPostsPage(ctx context.Context, p storage.PageParams) (*storage.PostPage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	posts := make([]domain.Post, 0, len(m.posts))
+	for _, post := range m.posts {
+		posts = append(posts, post)
+	}
+
+	window, hasNext, hasPrev := paginate(posts, storage.PostCursor, p)
+	return &storage.PostPage{
+		Posts:           window,
+		HasNextPage:     hasNext,
+		HasPreviousPage: hasPrev,
+		TotalCount:      len(posts),
+	}, nil
+}
+
+func (m *MemoryStorage) UpdatePost(ctx context.Context, post domain.Post) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	if _, ok := m.posts[post.ID]; !ok {
-		return ErrPostNotFound
+		return domain.ErrPostNotFound
 	}
 	m.posts[post.ID] = post
 	return nil
 }
 
-func (m *MemoryStorage) DeletePost(id uuid.UUID) error {
+func (m *MemoryStorage) DeletePost(ctx context.Context, id uuid.UUID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	if _, ok := m.posts[id]; !ok {
-		return ErrPostNotFound
+		return domain.ErrPostNotFound
 	}
 	delete(m.posts, id)
 
 	ids := m.commentsByPost[id]
 	for _, cid := range ids {
+		removeID(m.commentsByAuthor, m.commentsByID[cid].AuthorID, cid)
 		delete(m.commentsByID, cid)
+		delete(m.repliesByParent, cid)
+		delete(m.revisions, cid)
 	}
+	m.dropFlagsLocked(ids)
 	delete(m.commentsByPost, id)
 
 	return nil
 }
 
-func (m *MemoryStorage) CreateComment(c domain.Comment) error {
+func (m *MemoryStorage) CreateComment(ctx context.Context, c domain.Comment) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	post, ok := m.posts[c.PostID]
 	if !ok {
-		return ErrPostNotFound
+		return domain.ErrPostNotFound
 	}
 	if !post.CommentsAllowed {
-		return ErrCommentsDisabled
+		return domain.ErrCommentsDisabled
 	}
 
-	if len(c.Content) > 2000 {
-		return ErrCommentTooLong
+	if len(c.Content) > domain.MaxCommentLength {
+		return domain.ErrCommentTooLong
 	}
 
 	if c.ParentID != nil {
 		parent, ok := m.commentsByID[*c.ParentID]
 		if !ok {
-			return ErrCommentNotFound
+			return domain.ErrParentCommentNotFound
 		}
 		if parent.PostID != c.PostID {
-			return ErrParentCommentWrongPost
+			return domain.ErrParentCommentWrongPost
+		}
+		if parent.Deleted() {
+			return domain.ErrParentCommentDeleted
 		}
 	}
 
@@ -154,25 +233,55 @@ func (m *MemoryStorage) CreateComment(c domain.Comment) error {
 	if c.CreatedAt.IsZero() {
 		c.CreatedAt = time.Now()
 	}
+	if c.Status == "" {
+		c.Status = domain.StatusVisible
+	}
 
 	m.commentsByID[c.ID] = c
 	m.commentsByPost[c.PostID] = append(m.commentsByPost[c.PostID], c.ID)
+	if c.ParentID != nil {
+		m.repliesByParent[*c.ParentID] = append(m.repliesByParent[*c.ParentID], c.ID)
+	}
+	byAuthor := m.commentsByAuthor[c.AuthorID]
+	i := sort.Search(len(byAuthor), func(i int) bool {
+		return m.commentsByID[byAuthor[i]].CreatedAt.After(c.CreatedAt)
+	})
+	m.commentsByAuthor[c.AuthorID] = slices.Insert(byAuthor, i, c.ID)
 
 	return nil
 }
 
-func (m *MemoryStorage) GetComment(id uuid.UUID) (*domain.Comment, error) {
+func (m *MemoryStorage) GetComment(ctx context.Context, id uuid.UUID) (*domain.Comment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
 	comment, ok := m.commentsByID[id]
 	if !ok {
-		return nil, ErrCommentNotFound
+		return nil, domain.ErrCommentNotFound
 	}
 	return &comment, nil
 }
 
-func (m *MemoryStorage) GetComments(postID uuid.UUID, limit, offset int) ([]domain.Comment, error) {
+func (m *MemoryStorage) GetCommentsByIDs(ctx context.Context, ids []uuid.UUID) ([]domain.Comment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.commentsLocked(ids), nil
+}
+
+func (m *MemoryStorage) GetComments(ctx context.Context, postID uuid.UUID, limit, offset int, includeHidden bool) ([]domain.Comment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
@@ -183,63 +292,287 @@ func (m *MemoryStorage) GetComments(postID uuid.UUID, limit, offset int) ([]doma
 		return []domain.Comment{}, nil
 	}
 
-	ids := m.commentsByPost[postID]
+	comments := visible(m.commentsLocked(m.commentsByPost[postID]), includeHidden)
 
-	if offset >= len(ids) {
+	if offset >= len(comments) {
 		return []domain.Comment{}, nil
 	}
 
 	end := offset + limit
-	if end > len(ids) {
-		end = len(ids)
+	if end > len(comments) {
+		end = len(comments)
+	}
+	return comments[offset:end], nil
+}
+
+func (m *MemoryStorage) GetCommentsPage(ctx context.Context, postID uuid.UUID, p storage.PageParams, includeHidden bool) (*storage.CommentPage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	comments := visible(m.commentsLocked(m.commentsByPost[postID]), includeHidden)
+	window, hasNext, hasPrev := paginate(comments, storage.CommentCursor, p)
+	return &storage.CommentPage{
+		Comments:        window,
+		HasNextPage:     hasNext,
+		HasPreviousPage: hasPrev,
+		TotalCount:      len(comments),
+	}, nil
+}
+
+func (m *MemoryStorage) GetReplies(ctx context.Context, parentID uuid.UUID, p storage.PageParams, includeHidden bool) (*storage.CommentPage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	replies := visible(m.commentsLocked(m.repliesByParent[parentID]), includeHidden)
+	window, hasNext, hasPrev := paginate(replies, storage.CommentCursor, p)
+	return &storage.CommentPage{
+		Comments:        window,
+		HasNextPage:     hasNext,
+		HasPreviousPage: hasPrev,
+		TotalCount:      len(replies),
+	}, nil
+}
+
+func (m *MemoryStorage) CountReplies(ctx context.Context, commentID uuid.UUID, includeHidden bool) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
 	}
 
-	pageIDs := ids[offset:end]
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return len(visible(m.commentsLocked(m.repliesByParent[commentID]), includeHidden)), nil
+}
+
+func (m *MemoryStorage) CountRepliesByCommentIDs(ctx context.Context, ids []uuid.UUID, includeHidden bool) (map[uuid.UUID]int, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
-	result := make([]domain.Comment, 0, len(pageIDs))
-	for _, id := range pageIDs {
-		c, ok := m.commentsByID[id]
-		if ok {
-			result = append(result, c)
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.countsLocked(m.repliesByParent, ids, includeHidden), nil
+}
+
+func (m *MemoryStorage) CountCommentsByPostIDs(ctx context.Context, ids []uuid.UUID, includeHidden bool) (map[uuid.UUID]int, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.countsLocked(m.commentsByPost, ids, includeHidden), nil
+}
+
+// countsLocked returns the non-zero numbers of comments in index entries
+// of keys. m.mu must be held.
+func (m *MemoryStorage) countsLocked(index map[uuid.UUID][]uuid.UUID, keys []uuid.UUID, includeHidden bool) map[uuid.UUID]int {
+	counts := make(map[uuid.UUID]int, len(keys))
+	for _, k := range keys {
+		if n := len(visible(m.commentsLocked(index[k]), includeHidden)); n > 0 {
+			counts[k] = n
+		}
+	}
+	return counts
+}
+
+func (m *MemoryStorage) GetCommentTree(ctx context.Context, postID uuid.UUID, maxDepth, perNode int, includeHidden bool) ([]domain.Comment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if maxDepth <= 0 || perNode <= 0 {
+		return []domain.Comment{}, nil
+	}
+
+	var roots []domain.Comment
+	for _, c := range visible(m.commentsLocked(m.commentsByPost[postID]), includeHidden) {
+		if c.ParentID == nil {
+			roots = append(roots, c)
+		}
+	}
+
+	level, _, _ := paginate(roots, storage.CommentCursor, storage.PageParams{First: perNode})
+	out := make([]domain.Comment, 0, len(level))
+	for depth := 1; len(level) > 0; depth++ {
+		out = append(out, level...)
+		if depth == maxDepth {
+			break
+		}
+
+		var next []domain.Comment
+		for _, parent := range level {
+			replies := visible(m.commentsLocked(m.repliesByParent[parent.ID]), includeHidden)
+			window, _, _ := paginate(replies, storage.CommentCursor, storage.PageParams{First: perNode})
+			next = append(next, window...)
 		}
+		level = next
 	}
-	return result, nil
+	return out, nil
+}
 
+// visible drops hidden comments unless includeHidden is set.
+func visible(comments []domain.Comment, includeHidden bool) []domain.Comment {
+	if includeHidden {
+		return comments
+	}
+	out := comments[:0]
+	for _, c := range comments {
+		if c.Status != domain.StatusHidden {
+			out = append(out, c)
+		}
+	}
+	return out
 }
 
-func (m *MemoryStorage) UpdateComment(c domain.Comment) error {
+// commentsLocked resolves comment IDs. m.mu must be held.
+func (m *MemoryStorage) commentsLocked(ids []uuid.UUID) []domain.Comment {
+	comments := make([]domain.Comment, 0, len(ids))
+	for _, id := range ids {
+		if c, ok := m.commentsByID[id]; ok {
+			comments = append(comments, c)
+		}
+	}
+	return comments
+}
+
+func (m *MemoryStorage) UpdateComment(ctx context.Context, c domain.Comment, editorID uuid.UUID) (*domain.Comment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	old, ok := m.commentsByID[c.ID]
 	if !ok {
-		return ErrCommentNotFound
+		return nil, domain.ErrCommentNotFound
+	}
+	if old.Deleted() {
+		return nil, domain.ErrCommentDeleted
 	}
 
 	c.PostID = old.PostID
 	c.ParentID = old.ParentID
+	c.AuthorID = old.AuthorID
 	c.CreatedAt = old.CreatedAt
+	c.DeletedAt, c.DeletedBy = nil, uuid.Nil
+	c.Status = old.Status
 
-	if len(c.Content) > 2000 {
-		return ErrCommentTooLong
+	if len(c.Content) > domain.MaxCommentLength {
+		return nil, domain.ErrCommentTooLong
 	}
 
+	editedAt := time.Now()
+	if c.EditedAt != nil {
+		editedAt = *c.EditedAt
+	}
+	c.EditedAt = &editedAt
+	c.RevisionCount = old.RevisionCount + 1
+
+	// Version 7 IDs grow with time, which keeps edits made within the same
+	// timestamp in order.
+	m.revisions[c.ID] = append(m.revisions[c.ID], domain.CommentRevision{
+		ID:        uuid.Must(uuid.NewV7()),
+		CommentID: c.ID,
+		Content:   old.Content,
+		EditorID:  editorID,
+		CreatedAt: editedAt,
+	})
 	m.commentsByID[c.ID] = c
+	return &c, nil
+}
+
+func (m *MemoryStorage) GetCommentRevisions(ctx context.Context, commentID uuid.UUID, p storage.PageParams) (*storage.RevisionPage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	revisions := m.revisions[commentID]
+	window, hasNext, hasPrev := paginate(revisions, storage.RevisionCursor, p)
+	return &storage.RevisionPage{
+		Revisions:       window,
+		HasNextPage:     hasNext,
+		HasPreviousPage: hasPrev,
+		TotalCount:      len(revisions),
+	}, nil
+}
+
+func (m *MemoryStorage) DeleteComment(ctx context.Context, id, deletedBy uuid.UUID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, ok := m.commentsByID[id]
+	if !ok {
+		return domain.ErrCommentNotFound
+	}
+	if c.Deleted() {
+		return domain.ErrCommentDeleted
+	}
+
+	now := time.Now()
+	c.DeletedAt, c.DeletedBy = &now, deletedBy
+	m.commentsByID[id] = c
 	return nil
 }
 
-func (m *MemoryStorage) DeleteComment(id uuid.UUID) error {
+func (m *MemoryStorage) PurgeComment(ctx context.Context, id uuid.UUID) ([]uuid.UUID, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	c, ok := m.commentsByID[id]
 	if !ok {
-		return ErrCommentNotFound
+		return nil, domain.ErrCommentNotFound
+	}
+	if c.ParentID != nil {
+		removeID(m.repliesByParent, *c.ParentID, id)
 	}
 
-	delete(m.commentsByID, id)
+	// Replies go with the comment, as ON DELETE CASCADE does in postgres.
+	var purged []uuid.UUID
+	queue := []uuid.UUID{id}
+	for len(queue) > 0 {
+		cid := queue[0]
+		queue = append(queue[1:], m.repliesByParent[cid]...)
+
+		removeID(m.commentsByPost, c.PostID, cid)
+		removeID(m.commentsByAuthor, m.commentsByID[cid].AuthorID, cid)
+		delete(m.commentsByID, cid)
+		delete(m.repliesByParent, cid)
+		delete(m.revisions, cid)
+		purged = append(purged, cid)
+	}
+	m.dropFlagsLocked(purged)
+	return purged, nil
+}
 
-	ids := m.commentsByPost[c.PostID]
+// removeID removes id from the index list stored under key.
+func removeID(index map[uuid.UUID][]uuid.UUID, key, id uuid.UUID) {
+	ids := index[key]
 	for i := 0; i < len(ids); i++ {
 		if ids[i] == id {
 			ids = append(ids[:i], ids[i+1:]...)
@@ -248,9 +581,8 @@ func (m *MemoryStorage) DeleteComment(id uuid.UUID) error {
 	}
 
 	if len(ids) == 0 {
-		delete(m.commentsByPost, c.PostID)
+		delete(index, key)
 	} else {
-		m.commentsByPost[c.PostID] = ids
+		index[key] = ids
 	}
-	return nil
 }
@@ -0,0 +1,67 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"posts-comments-1/internal/domain"
+)
+
+func (m *MemoryStorage) UpsertUser(ctx context.Context, u domain.User) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if old, ok := m.users[u.ID]; ok {
+		if u.Name == "" {
+			return nil
+		}
+		old.Name = u.Name
+		m.users[u.ID] = old
+		return nil
+	}
+
+	if u.CreatedAt.IsZero() {
+		u.CreatedAt = time.Now()
+	}
+	u.Roles = nil
+	m.users[u.ID] = u
+	return nil
+}
+
+func (m *MemoryStorage) GetUser(ctx context.Context, id uuid.UUID) (*domain.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	u, ok := m.users[id]
+	if !ok {
+		return nil, domain.ErrUserNotFound
+	}
+	return &u, nil
+}
+
+func (m *MemoryStorage) GetUsersByIDs(ctx context.Context, ids []uuid.UUID) ([]domain.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	users := make([]domain.User, 0, len(ids))
+	for _, id := range ids {
+		if u, ok := m.users[id]; ok {
+			users = append(users, u)
+		}
+	}
+	return users, nil
+}
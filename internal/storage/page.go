@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"posts-comments-1/internal/domain"
+)
+
+var ErrInvalidCursor = domain.NewError(domain.KindValidation, "invalid cursor")
+
+// Cursor is a position in a list ordered by (created_at, id), which is the
+// order of every keyset-paginated list in the storage.
+type Cursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+func PostCursor(p domain.Post) Cursor {
+	return Cursor{CreatedAt: p.CreatedAt, ID: p.ID}
+}
+
+func CommentCursor(c domain.Comment) Cursor {
+	return Cursor{CreatedAt: c.CreatedAt, ID: c.ID}
+}
+
+func RevisionCursor(r domain.CommentRevision) Cursor {
+	return Cursor{CreatedAt: r.CreatedAt, ID: r.ID}
+}
+
+func FlagCursor(f domain.CommentFlag) Cursor {
+	return Cursor{CreatedAt: f.CreatedAt, ID: f.ID}
+}
+
+// Less reports whether c sorts before o.
+func (c Cursor) Less(o Cursor) bool {
+	if c.CreatedAt.Equal(o.CreatedAt) {
+		return c.ID.String() < o.ID.String()
+	}
+	return c.CreatedAt.Before(o.CreatedAt)
+}
+
+// Encode returns the opaque string form of the cursor handed out to clients.
+func (c Cursor) Encode() string {
+	raw := strconv.FormatInt(c.CreatedAt.UnixNano(), 10) + ":" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeCursor(s string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	ts, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return Cursor{}, ErrInvalidCursor
+	}
+	nanos, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	return Cursor{CreatedAt: time.Unix(0, nanos), ID: uid}, nil
+}
+
+// PageParams selects a keyset page. When Last is set the page holds the last
+// items before Before, otherwise the first First items after After. After and
+// Before bound the window in both directions when both are given.
+type PageParams struct {
+	First  int
+	After  *Cursor
+	Last   int
+	Before *Cursor
+}
+
+func (p PageParams) Backward() bool {
+	return p.Last > 0
+}
+
+func (p PageParams) Limit() int {
+	if p.Backward() {
+		return p.Last
+	}
+	return p.First
+}
+
+type PostPage struct {
+	Posts           []domain.Post
+	HasNextPage     bool
+	HasPreviousPage bool
+	TotalCount      int
+}
+
+type CommentPage struct {
+	Comments        []domain.Comment
+	HasNextPage     bool
+	HasPreviousPage bool
+	TotalCount      int
+}
+
+type RevisionPage struct {
+	Revisions       []domain.CommentRevision
+	HasNextPage     bool
+	HasPreviousPage bool
+	TotalCount      int
+}
+
+type FlagPage struct {
+	Flags           []domain.CommentFlag
+	HasNextPage     bool
+	HasPreviousPage bool
+	TotalCount      int
+}
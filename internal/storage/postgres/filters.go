@@ -0,0 +1,59 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"posts-comments-1/internal/domain"
+)
+
+// spaceChars are the characters unicode.IsSpace reports, which
+// strings.TrimSpace removes and the memory storage ignores.
+const spaceChars = "\t\n\v\f\r \u0085\u00a0\u1680\u2000\u2001\u2002\u2003\u2004\u2005\u2006\u2007\u2008\u2009\u200a\u2028\u2029\u202f\u205f\u3000"
+
+func (s *Storage) HasRecentComment(ctx context.Context, authorID uuid.UUID, content string, since time.Time) (bool, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	const q = `
+SELECT EXISTS (
+  SELECT 1
+  FROM comments
+  WHERE author_id = $1
+    AND created_at >= $2
+    AND deleted_at IS NULL
+    AND lower(btrim(content, $4)) = lower(btrim($3, $4))
+);
+`
+	var exists bool
+	if err := s.db.QueryRow(ctx, q, authorID, since, content, spaceChars).Scan(&exists); err != nil {
+		return false, fmt.Errorf("find recent comment: %w", err)
+	}
+	return exists, nil
+}
+
+func (s *Storage) RecordFilterDecisions(ctx context.Context, decisions []domain.FilterDecision) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	rows := make([][]any, 0, len(decisions))
+	for _, d := range decisions {
+		if d.ID == uuid.Nil {
+			d.ID = uuid.New()
+		}
+		if d.CreatedAt.IsZero() {
+			d.CreatedAt = time.Now().Truncate(time.Microsecond)
+		}
+		rows = append(rows, []any{d.ID, d.CommentID, d.PostID, nullUUID(d.AuthorID), d.Filter, string(d.Action), d.Reason, d.CreatedAt})
+	}
+
+	columns := []string{"id", "comment_id", "post_id", "author_id", "filter", "action", "reason", "created_at"}
+	if _, err := s.db.CopyFrom(ctx, pgx.Identifier{"comment_filter_decisions"}, columns, pgx.CopyFromRows(rows)); err != nil {
+		return fmt.Errorf("record filter decisions: %w", err)
+	}
+	return nil
+}
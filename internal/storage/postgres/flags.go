@@ -0,0 +1,186 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"posts-comments-1/internal/domain"
+	"posts-comments-1/internal/storage"
+)
+
+const flagColumns = `id, comment_id, reporter_id, reason, created_at, resolved_at, resolved_by, resolution`
+
+func scanFlag(row pgx.Row) (domain.CommentFlag, error) {
+	var (
+		f          domain.CommentFlag
+		reporter   *uuid.UUID
+		resolvedBy *uuid.UUID
+		resolution *string
+	)
+	err := row.Scan(&f.ID, &f.CommentID, &reporter, &f.Reason, &f.CreatedAt, &f.ResolvedAt, &resolvedBy, &resolution)
+	f.ReporterID = fromNullUUID(reporter)
+	f.ResolvedBy = fromNullUUID(resolvedBy)
+	if resolution != nil {
+		f.Resolution = domain.ModerationStatus(*resolution)
+	}
+	return f, err
+}
+
+func (s *Storage) FlagComment(ctx context.Context, f domain.CommentFlag) error {
+	if f.ID == uuid.Nil {
+		f.ID = uuid.New()
+	}
+	if f.CreatedAt.IsZero() {
+		f.CreatedAt = time.Now().Truncate(time.Microsecond)
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	const qLock = `
+SELECT deleted_at IS NOT NULL
+FROM comments
+WHERE id = $1
+FOR UPDATE;
+`
+	const qInsert = `
+INSERT INTO comment_flags (id, comment_id, reporter_id, reason, created_at)
+VALUES ($1, $2, $3, $4, $5);
+`
+	const qPending = `
+UPDATE comments
+SET moderation_status = 'pending'
+WHERE id = $1 AND moderation_status = 'visible';
+`
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		var deleted bool
+		if err := tx.QueryRow(ctx, qLock, f.CommentID).Scan(&deleted); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return domain.ErrCommentNotFound
+			}
+			return fmt.Errorf("lock comment: %w", err)
+		}
+		if deleted {
+			return domain.ErrCommentDeleted
+		}
+		if _, err := tx.Exec(ctx, qInsert, f.ID, f.CommentID, nullUUID(f.ReporterID), f.Reason, f.CreatedAt); err != nil {
+			return fmt.Errorf("insert flag: %w", err)
+		}
+		if _, err := tx.Exec(ctx, qPending, f.CommentID); err != nil {
+			return fmt.Errorf("mark comment pending: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		if domain.KindOf(err) != "" {
+			return err
+		}
+		return fmt.Errorf("flag comment: %w", err)
+	}
+	return nil
+}
+
+func (s *Storage) GetOpenFlags(ctx context.Context, p storage.PageParams) (*storage.FlagPage, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	page := &storage.FlagPage{Flags: []domain.CommentFlag{}}
+
+	const qCount = `SELECT count(*) FROM comment_flags WHERE resolved_at IS NULL;`
+	if err := s.db.QueryRow(ctx, qCount).Scan(&page.TotalCount); err != nil {
+		return nil, fmt.Errorf("count open flags: %w", err)
+	}
+	if p.Limit() <= 0 {
+		return page, nil
+	}
+
+	tail, args := keyset([]string{"resolved_at IS NULL"}, nil, p)
+	q := `
+SELECT ` + flagColumns + `
+FROM comment_flags
+` + tail
+	rows, err := s.db.Query(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("get open flags: %w", err)
+	}
+	out, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.CommentFlag, error) {
+		return scanFlag(row)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get open flags: %w", err)
+	}
+
+	page.Flags, page.HasNextPage, page.HasPreviousPage = trimPage(out, p)
+	return page, nil
+}
+
+func (s *Storage) ResolveFlag(ctx context.Context, flagID, resolvedBy uuid.UUID, status domain.ModerationStatus) (*domain.Comment, error) {
+	if !domain.ValidResolution(status) {
+		return nil, domain.ErrInvalidResolution
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	// Resolving one flag resolves every open flag of the comment: the
+	// decision is about the comment, not the report.
+	const qResolve = `
+UPDATE comment_flags
+SET resolved_at = now(), resolved_by = $2, resolution = $3
+WHERE comment_id = (SELECT comment_id FROM comment_flags WHERE id = $1)
+  AND resolved_at IS NULL
+RETURNING id, comment_id;
+`
+	const qExists = `SELECT EXISTS (SELECT 1 FROM comment_flags WHERE id = $1);`
+	const qStatus = `
+UPDATE comments
+SET moderation_status = $2
+WHERE id = $1
+RETURNING ` + commentColumns + `;
+`
+	var updated domain.Comment
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, qResolve, flagID, nullUUID(resolvedBy), string(status))
+		if err != nil {
+			return fmt.Errorf("resolve flags: %w", err)
+		}
+		type resolved struct {
+			ID        uuid.UUID
+			CommentID uuid.UUID
+		}
+		out, err := pgx.CollectRows(rows, pgx.RowToStructByPos[resolved])
+		if err != nil {
+			return fmt.Errorf("resolve flags: %w", err)
+		}
+		i := slices.IndexFunc(out, func(r resolved) bool { return r.ID == flagID })
+		if i < 0 {
+			var exists bool
+			if err := tx.QueryRow(ctx, qExists, flagID).Scan(&exists); err != nil {
+				return fmt.Errorf("resolve flags: %w", err)
+			}
+			if !exists {
+				return domain.ErrFlagNotFound
+			}
+			return domain.ErrFlagResolved
+		}
+
+		updated, err = scanComment(tx.QueryRow(ctx, qStatus, out[i].CommentID, string(status)))
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.ErrCommentNotFound
+		}
+		return err
+	})
+	if err != nil {
+		if domain.KindOf(err) != "" {
+			return nil, err
+		}
+		return nil, fmt.Errorf("resolve flag: %w", err)
+	}
+	return &updated, nil
+}
@@ -0,0 +1,55 @@
+package postgres
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"posts-comments-1/internal/storage"
+)
+
+// keyset builds the WHERE / ORDER BY / LIMIT tail of a query over rows
+// ordered by (created_at, id), so that it can use the matching indexes.
+// conds and args are the filters the caller already has; one extra row is
+// requested to detect whether there is another page.
+func keyset(conds []string, args []any, p storage.PageParams) (string, []any) {
+	if p.After != nil {
+		args = append(args, p.After.CreatedAt, p.After.ID)
+		conds = append(conds, fmt.Sprintf("(created_at, id) > ($%d, $%d)", len(args)-1, len(args)))
+	}
+	if p.Before != nil {
+		args = append(args, p.Before.CreatedAt, p.Before.ID)
+		conds = append(conds, fmt.Sprintf("(created_at, id) < ($%d, $%d)", len(args)-1, len(args)))
+	}
+
+	var b strings.Builder
+	if len(conds) > 0 {
+		b.WriteString("WHERE " + strings.Join(conds, " AND ") + "\n")
+	}
+	if p.Backward() {
+		b.WriteString("ORDER BY created_at DESC, id DESC\n")
+	} else {
+		b.WriteString("ORDER BY created_at, id\n")
+	}
+	args = append(args, p.Limit()+1)
+	fmt.Fprintf(&b, "LIMIT $%d;", len(args))
+
+	return b.String(), args
+}
+
+// trimPage drops the look-ahead row fetched by keyset and restores the
+// ascending order of backward pages. It returns hasNextPage and
+// hasPreviousPage.
+func trimPage[T any](rows []T, p storage.PageParams) ([]T, bool, bool) {
+	limit := p.Limit()
+	more := len(rows) > limit
+	if more {
+		rows = rows[:limit]
+	}
+
+	if p.Backward() {
+		slices.Reverse(rows)
+		return rows, p.Before != nil, more
+	}
+	return rows, more, p.After != nil
+}
@@ -0,0 +1,125 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"posts-comments-1/internal/domain"
+)
+
+// Column lists matching scanPost and scanComment.
+const (
+	postColumns    = `id, title, content, comments_allowed, created_at, author_id`
+	commentColumns = `id, post_id, parent_id, content, created_at, author_id, edited_at, revision_count, deleted_at, deleted_by, moderation_status`
+)
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanPost(row rowScanner) (domain.Post, error) {
+	var (
+		p      domain.Post
+		author *uuid.UUID
+	)
+	err := row.Scan(&p.ID, &p.Title, &p.Content, &p.CommentsAllowed, &p.CreatedAt, &author)
+	p.AuthorID = fromNullUUID(author)
+	return p, err
+}
+
+func scanComment(row rowScanner) (domain.Comment, error) {
+	var (
+		c                 domain.Comment
+		author, deletedBy *uuid.UUID
+	)
+	err := row.Scan(&c.ID, &c.PostID, &c.ParentID, &c.Content, &c.CreatedAt, &author, &c.EditedAt, &c.RevisionCount, &c.DeletedAt, &deletedBy, &c.Status)
+	c.AuthorID = fromNullUUID(author)
+	c.DeletedBy = fromNullUUID(deletedBy)
+	return c, err
+}
+
+// nullUUID maps uuid.Nil, used by the domain for "no value", to SQL NULL.
+func nullUUID(id uuid.UUID) *uuid.UUID {
+	if id == uuid.Nil {
+		return nil
+	}
+	return &id
+}
+
+func fromNullUUID(id *uuid.UUID) uuid.UUID {
+	if id == nil {
+		return uuid.Nil
+	}
+	return *id
+}
+
+// queryPosts runs q and scans rows of postColumns.
+func (s *Storage) queryPosts(ctx context.Context, q string, args ...any) ([]domain.Post, error) {
+	rows, err := s.db.Query(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	defer rows.Close()
+
+	out := make([]domain.Post, 0)
+	for rows.Next() {
+		p, err := scanPost(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		out = append(out, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows: %w", err)
+	}
+	return out, nil
+}
+
+// queryComments runs q and scans rows of commentColumns.
+func (s *Storage) queryComments(ctx context.Context, q string, args ...any) ([]domain.Comment, error) {
+	rows, err := s.db.Query(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	defer rows.Close()
+
+	out := make([]domain.Comment, 0)
+	for rows.Next() {
+		c, err := scanComment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		out = append(out, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows: %w", err)
+	}
+	return out, nil
+}
+
+// queryCounts runs q and collects rows of (id, count).
+func (s *Storage) queryCounts(ctx context.Context, q string, args ...any) (map[uuid.UUID]int, error) {
+	rows, err := s.db.Query(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	defer rows.Close()
+
+	out := make(map[uuid.UUID]int)
+	for rows.Next() {
+		var (
+			id uuid.UUID
+			n  int
+		)
+		if err := rows.Scan(&id, &n); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		out[id] = n
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows: %w", err)
+	}
+	return out, nil
+}
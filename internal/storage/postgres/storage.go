@@ -4,36 +4,45 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"os"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/multitracer"
 	"github.com/jackc/pgx/v5/pgxpool"
 
+	"posts-comments-1/internal/config"
 	"posts-comments-1/internal/domain"
-)
-
-var (
-	ErrPostNotFound           = errors.New("post not found")
-	ErrCommentNotFound        = errors.New("comment not found")
-	ErrCommentTooLong         = errors.New("comment content exceeds maximum length")
-	ErrCommentsDisabled       = errors.New("comments are disabled")
-	ErrParentCommentWrongPost = errors.New("parent comment belongs to another post")
-	ErrParentCommentNotFound  = errors.New("parent comment not found")
+	"posts-comments-1/internal/logging"
+	"posts-comments-1/internal/storage"
+	"posts-comments-1/internal/tracing"
 )
 
 type Storage struct {
-	db *pgxpool.Pool
+	db           *pgxpool.Pool
+	queryTimeout time.Duration
 }
 
-func New() (*Storage, error) {
-	dsn := dsnFromEnv()
+func New(cfg config.PostgresConfig) (*Storage, error) {
+	poolCfg, err := pgxpool.ParseConfig(cfg.ConnString())
+	if err != nil {
+		return nil, fmt.Errorf("parse postgres config: %w", err)
+	}
+	poolCfg.MaxConns = cfg.MaxConns
+	poolCfg.MinConns = cfg.MinConns
+	if cfg.MaxConnLifetime > 0 {
+		poolCfg.MaxConnLifetime = cfg.MaxConnLifetime
+	}
+	if cfg.MaxConnIdleTime > 0 {
+		poolCfg.MaxConnIdleTime = cfg.MaxConnIdleTime
+	}
+	poolCfg.ConnConfig.ConnectTimeout = cfg.ConnectTimeout
+	poolCfg.ConnConfig.Tracer = multitracer.New(tracing.PgxTracer{}, logging.PgxTracer{})
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ConnectTimeout)
 	defer cancel()
 
-	pool, err := pgxpool.New(ctx, dsn)
+	pool, err := pgxpool.NewWithConfig(ctx, poolCfg)
 	if err != nil {
 		return nil, fmt.Errorf("connect postgres: %w", err)
 	}
@@ -43,7 +52,7 @@ func New() (*Storage, error) {
 		return nil, fmt.Errorf("ping postgres: %w", err)
 	}
 
-	return &Storage{db: pool}, nil
+	return &Storage{db: pool, queryTimeout: cfg.QueryTimeout}, nil
 }
 
 func (s *Storage) Close() {
@@ -52,71 +61,89 @@ func (s *Storage) Close() {
 	}
 }
 
-func dsnFromEnv() string {
-	host := getenv("POSTGRES_HOST", "localhost")
-	port := getenv("POSTGRES_PORT", "5432")
-	user := getenv("POSTGRES_USER", "postgres")
-	pass := getenv("POSTGRES_PASSWORD", "pass")
-	db := getenv("POSTGRES_DB", "postgres")
-
-	return fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable", user, pass, host, port, db)
+// Pool exposes the connection pool for components that share the database,
+// such as the LISTEN/NOTIFY pub/sub.
+func (s *Storage) Pool() *pgxpool.Pool {
+	return s.db
 }
 
-func getenv(key, def string) string {
-	v := os.Getenv(key)
-	if v == "" {
-		return def
+var _ storage.HealthChecker = (*Storage)(nil)
+
+// Ping checks that a connection to the database can be used.
+func (s *Storage) Ping(ctx context.Context) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	if err := s.db.Ping(ctx); err != nil {
+		return fmt.Errorf("ping postgres: %w", err)
 	}
-	return v
+	return nil
 }
 
-func withTimeout() (context.Context, context.CancelFunc) {
-	return context.WithTimeout(context.Background(), 3*time.Second)
+// withTimeout bounds a single storage call by the configured query timeout
+// while still honouring cancellation and deadlines of the caller's ctx.
+func (s *Storage) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, s.queryTimeout)
 }
 
-func (s *Storage) CreatePost(p domain.Post) error {
+func (s *Storage) CreatePost(ctx context.Context, p domain.Post) error {
 	if p.ID == uuid.Nil {
 		p.ID = uuid.New()
 	}
 	if p.CreatedAt.IsZero() {
-		p.CreatedAt = time.Now()
+		p.CreatedAt = time.Now().Truncate(time.Microsecond)
 	}
 
-	ctx, cancel := withTimeout()
+	ctx, cancel := s.withTimeout(ctx)
 	defer cancel()
 
 	const q = `
-INSERT INTO posts (id, title, content, comments_allowed, created_at)
-VALUES ($1, $2, $3, $4, $5);
+INSERT INTO posts (id, title, content, comments_allowed, created_at, author_id)
+VALUES ($1, $2, $3, $4, $5, $6);
 `
-	_, err := s.db.Exec(ctx, q, p.ID, p.Title, p.Content, p.CommentsAllowed, p.CreatedAt)
+	_, err := s.db.Exec(ctx, q, p.ID, p.Title, p.Content, p.CommentsAllowed, p.CreatedAt, nullUUID(p.AuthorID))
 	if err != nil {
 		return fmt.Errorf("create post: %w", err)
 	}
 	return nil
 }
 
-func (s *Storage) GetPost(id uuid.UUID) (*domain.Post, error) {
-	ctx, cancel := withTimeout()
+func (s *Storage) GetPost(ctx context.Context, id uuid.UUID) (*domain.Post, error) {
+	ctx, cancel := s.withTimeout(ctx)
 	defer cancel()
 
 	const q = `
-SELECT id, title, content, comments_allowed, created_at
+SELECT ` + postColumns + `
 FROM posts
 WHERE id = $1;
 `
-	var p domain.Post
-	err := s.db.QueryRow(ctx, q, id).Scan(&p.ID, &p.Title, &p.Content, &p.CommentsAllowed, &p.CreatedAt)
+	p, err := scanPost(s.db.QueryRow(ctx, q, id))
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, ErrPostNotFound
+			return nil, domain.ErrPostNotFound
 		}
 		return nil, fmt.Errorf("get post: %w", err)
 	}
 	return &p, nil
 }
 
-func (s *Storage) ListPosts(limit, offset int) ([]domain.Post, error) {
+func (s *Storage) GetPostsByIDs(ctx context.Context, ids []uuid.UUID) ([]domain.Post, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	const q = `
+SELECT ` + postColumns + `
+FROM posts
+WHERE id = ANY($1);
+`
+	posts, err := s.queryPosts(ctx, q, ids)
+	if err != nil {
+		return nil, fmt.Errorf("get posts by ids: %w", err)
+	}
+	return posts, nil
+}
+
+func (s *Storage) ListPosts(ctx context.Context, limit, offset int) ([]domain.Post, error) {
 	if offset < 0 {
 		offset = 0
 	}
@@ -124,38 +151,52 @@ func (s *Storage) ListPosts(limit, offset int) ([]domain.Post, error) {
 		return []domain.Post{}, nil
 	}
 
-	ctx, cancel := withTimeout()
+	ctx, cancel := s.withTimeout(ctx)
 	defer cancel()
 
 	const q = `
-SELECT id, title, content, comments_allowed, created_at
+SELECT ` + postColumns + `
 FROM posts
 ORDER BY created_at, id
 LIMIT $1 OFFSET $2;
 `
-	rows, err := s.db.Query(ctx, q, limit, offset)
+	out, err := s.queryPosts(ctx, q, limit, offset)
 	if err != nil {
-		return nil, fmt.Errorf("list posts query: %w", err)
+		return nil, fmt.Errorf("list posts: %w", err)
 	}
-	defer rows.Close()
+	return out, nil
+}
 
-	out := make([]domain.Post, 0, limit)
-	for rows.Next() {
-		var p domain.Post
-		if err := rows.Scan(&p.ID, &p.Title, &p.Content, &p.CommentsAllowed, &p.CreatedAt); err != nil {
-			return nil, fmt.Errorf("list posts scan: %w", err)
-		}
-		out = append(out, p)
+func (s *Storage) ListPostsPage(ctx context.Context, p storage.PageParams) (*storage.PostPage, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	page := &storage.PostPage{Posts: []domain.Post{}}
+
+	const qCount = `SELECT count(*) FROM posts;`
+	if err := s.db.QueryRow(ctx, qCount).Scan(&page.TotalCount); err != nil {
+		return nil, fmt.Errorf("count posts: %w", err)
 	}
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("list posts rows: %w", err)
+	if p.Limit() <= 0 {
+		return page, nil
 	}
 
-	return out, nil
+	tail, args := keyset(nil, nil, p)
+	q := `
+SELECT ` + postColumns + `
+FROM posts
+` + tail
+	out, err := s.queryPosts(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list posts page: %w", err)
+	}
+
+	page.Posts, page.HasNextPage, page.HasPreviousPage = trimPage(out, p)
+	return page, nil
 }
 
-func (s *Storage) UpdatePost(p domain.Post) error {
-	ctx, cancel := withTimeout()
+func (s *Storage) UpdatePost(ctx context.Context, p domain.Post) error {
+	ctx, cancel := s.withTimeout(ctx)
 	defer cancel()
 
 	const q = `
@@ -170,13 +211,13 @@ WHERE id = $1;
 		return fmt.Errorf("update post: %w", err)
 	}
 	if tag.RowsAffected() == 0 {
-		return ErrPostNotFound
+		return domain.ErrPostNotFound
 	}
 	return nil
 }
 
-func (s *Storage) DeletePost(id uuid.UUID) error {
-	ctx, cancel := withTimeout()
+func (s *Storage) DeletePost(ctx context.Context, id uuid.UUID) error {
+	ctx, cancel := s.withTimeout(ctx)
 	defer cancel()
 
 	const q = `DELETE FROM posts WHERE id = $1;`
@@ -185,24 +226,27 @@ func (s *Storage) DeletePost(id uuid.UUID) error {
 		return fmt.Errorf("delete post: %w", err)
 	}
 	if tag.RowsAffected() == 0 {
-		return ErrPostNotFound
+		return domain.ErrPostNotFound
 	}
 	return nil
 }
 
-func (s *Storage) CreateComment(c domain.Comment) error {
-	if len(c.Content) > 2000 {
-		return ErrCommentTooLong
+func (s *Storage) CreateComment(ctx context.Context, c domain.Comment) error {
+	if len(c.Content) > domain.MaxCommentLength {
+		return domain.ErrCommentTooLong
 	}
 
 	if c.ID == uuid.Nil {
 		c.ID = uuid.New()
 	}
 	if c.CreatedAt.IsZero() {
-		c.CreatedAt = time.Now()
+		c.CreatedAt = time.Now().Truncate(time.Microsecond)
+	}
+	if c.Status == "" {
+		c.Status = domain.StatusVisible
 	}
 
-	ctx, cancel := withTimeout()
+	ctx, cancel := s.withTimeout(ctx)
 	defer cancel()
 
 	const qPost = `
@@ -213,38 +257,44 @@ WHERE id = $1;
 	var allowed bool
 	if err := s.db.QueryRow(ctx, qPost, c.PostID).Scan(&allowed); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return ErrPostNotFound
+			return domain.ErrPostNotFound
 		}
 		return fmt.Errorf("check post for comment: %w", err)
 	}
 	if !allowed {
-		return ErrCommentsDisabled
+		return domain.ErrCommentsDisabled
 	}
 
 	if c.ParentID != nil {
 		const qParent = `
-SELECT post_id
+SELECT post_id, deleted_at IS NOT NULL
 FROM comments
 WHERE id = $1;
 `
-		var parentPostID uuid.UUID
-		err := s.db.QueryRow(ctx, qParent, *c.ParentID).Scan(&parentPostID)
+		var (
+			parentPostID  uuid.UUID
+			parentDeleted bool
+		)
+		err := s.db.QueryRow(ctx, qParent, *c.ParentID).Scan(&parentPostID, &parentDeleted)
 		if err != nil {
 			if errors.Is(err, pgx.ErrNoRows) {
-				return ErrParentCommentNotFound
+				return domain.ErrParentCommentNotFound
 			}
 			return fmt.Errorf("check parent comment: %w", err)
 		}
 		if parentPostID != c.PostID {
-			return ErrParentCommentWrongPost
+			return domain.ErrParentCommentWrongPost
+		}
+		if parentDeleted {
+			return domain.ErrParentCommentDeleted
 		}
 	}
 
 	const qInsert = `
-INSERT INTO comments (id, post_id, parent_id, content, created_at)
-VALUES ($1, $2, $3, $4, $5);
+INSERT INTO comments (id, post_id, parent_id, content, created_at, author_id, moderation_status)
+VALUES ($1, $2, $3, $4, $5, $6, $7);
 `
-	_, err := s.db.Exec(ctx, qInsert, c.ID, c.PostID, c.ParentID, c.Content, c.CreatedAt)
+	_, err := s.db.Exec(ctx, qInsert, c.ID, c.PostID, c.ParentID, c.Content, c.CreatedAt, nullUUID(c.AuthorID), c.Status)
 	if err != nil {
 		return fmt.Errorf("create comment: %w", err)
 	}
@@ -252,27 +302,42 @@ VALUES ($1, $2, $3, $4, $5);
 	return nil
 }
 
-func (s *Storage) GetComment(id uuid.UUID) (*domain.Comment, error) {
-	ctx, cancel := withTimeout()
+func (s *Storage) GetComment(ctx context.Context, id uuid.UUID) (*domain.Comment, error) {
+	ctx, cancel := s.withTimeout(ctx)
 	defer cancel()
 
 	const q = `
-SELECT id, post_id, parent_id, content, created_at
+SELECT ` + commentColumns + `
 FROM comments
 WHERE id = $1;
 `
-	var c domain.Comment
-	err := s.db.QueryRow(ctx, q, id).Scan(&c.ID, &c.PostID, &c.ParentID, &c.Content, &c.CreatedAt)
+	c, err := scanComment(s.db.QueryRow(ctx, q, id))
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, ErrCommentNotFound
+			return nil, domain.ErrCommentNotFound
 		}
 		return nil, fmt.Errorf("get comment: %w", err)
 	}
 	return &c, nil
 }
 
-func (s *Storage) GetComments(postID uuid.UUID, limit, offset int) ([]domain.Comment, error) {
+func (s *Storage) GetCommentsByIDs(ctx context.Context, ids []uuid.UUID) ([]domain.Comment, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	const q = `
+SELECT ` + commentColumns + `
+FROM comments
+WHERE id = ANY($1);
+`
+	comments, err := s.queryComments(ctx, q, ids)
+	if err != nil {
+		return nil, fmt.Errorf("get comments by ids: %w", err)
+	}
+	return comments, nil
+}
+
+func (s *Storage) GetComments(ctx context.Context, postID uuid.UUID, limit, offset int, includeHidden bool) ([]domain.Comment, error) {
 	if offset < 0 {
 		offset = 0
 	}
@@ -280,71 +345,326 @@ func (s *Storage) GetComments(postID uuid.UUID, limit, offset int) ([]domain.Com
 		return []domain.Comment{}, nil
 	}
 
-	ctx, cancel := withTimeout()
+	ctx, cancel := s.withTimeout(ctx)
 	defer cancel()
 
 	const q = `
-SELECT id, post_id, parent_id, content, created_at
+SELECT ` + commentColumns + `
 FROM comments
-WHERE post_id = $1
+WHERE post_id = $1 AND ($4 OR moderation_status <> 'hidden')
 ORDER BY created_at, id
 LIMIT $2 OFFSET $3;
 `
-	rows, err := s.db.Query(ctx, q, postID, limit, offset)
+	out, err := s.queryComments(ctx, q, postID, limit, offset, includeHidden)
 	if err != nil {
-		return nil, fmt.Errorf("get comments query: %w", err)
+		return nil, fmt.Errorf("get comments: %w", err)
 	}
-	defer rows.Close()
+	return out, nil
+}
 
-	out := make([]domain.Comment, 0, limit)
-	for rows.Next() {
-		var c domain.Comment
-		if err := rows.Scan(&c.ID, &c.PostID, &c.ParentID, &c.Content, &c.CreatedAt); err != nil {
-			return nil, fmt.Errorf("get comments scan: %w", err)
-		}
-		out = append(out, c)
+func (s *Storage) GetCommentsPage(ctx context.Context, postID uuid.UUID, p storage.PageParams, includeHidden bool) (*storage.CommentPage, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	page := &storage.CommentPage{Comments: []domain.Comment{}}
+
+	const qCount = `SELECT count(*) FROM comments WHERE post_id = $1 AND ($2 OR moderation_status <> 'hidden');`
+	if err := s.db.QueryRow(ctx, qCount, postID, includeHidden).Scan(&page.TotalCount); err != nil {
+		return nil, fmt.Errorf("count comments: %w", err)
 	}
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("get comments rows: %w", err)
+	if p.Limit() <= 0 {
+		return page, nil
 	}
 
-	return out, nil
+	tail, args := keyset([]string{"post_id = $1", "($2 OR moderation_status <> 'hidden')"}, []any{postID, includeHidden}, p)
+	q := `
+SELECT ` + commentColumns + `
+FROM comments
+` + tail
+	out, err := s.queryComments(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("get comments page: %w", err)
+	}
+
+	page.Comments, page.HasNextPage, page.HasPreviousPage = trimPage(out, p)
+	return page, nil
+}
+
+func (s *Storage) GetReplies(ctx context.Context, parentID uuid.UUID, p storage.PageParams, includeHidden bool) (*storage.CommentPage, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	page := &storage.CommentPage{Comments: []domain.Comment{}}
+
+	const qCount = `SELECT count(*) FROM comments WHERE parent_id = $1 AND ($2 OR moderation_status <> 'hidden');`
+	if err := s.db.QueryRow(ctx, qCount, parentID, includeHidden).Scan(&page.TotalCount); err != nil {
+		return nil, fmt.Errorf("count replies: %w", err)
+	}
+	if p.Limit() <= 0 {
+		return page, nil
+	}
+
+	tail, args := keyset([]string{"parent_id = $1", "($2 OR moderation_status <> 'hidden')"}, []any{parentID, includeHidden}, p)
+	q := `
+SELECT ` + commentColumns + `
+FROM comments
+` + tail
+	out, err := s.queryComments(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("get replies: %w", err)
+	}
+
+	page.Comments, page.HasNextPage, page.HasPreviousPage = trimPage(out, p)
+	return page, nil
 }
 
-func (s *Storage) UpdateComment(c domain.Comment) error {
-	if len(c.Content) > 2000 {
-		return ErrCommentTooLong
+func (s *Storage) CountReplies(ctx context.Context, commentID uuid.UUID, includeHidden bool) (int, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	const q = `SELECT count(*) FROM comments WHERE parent_id = $1 AND ($2 OR moderation_status <> 'hidden');`
+	var n int
+	if err := s.db.QueryRow(ctx, q, commentID, includeHidden).Scan(&n); err != nil {
+		return 0, fmt.Errorf("count replies: %w", err)
 	}
+	return n, nil
+}
 
-	ctx, cancel := withTimeout()
+func (s *Storage) CountRepliesByCommentIDs(ctx context.Context, ids []uuid.UUID, includeHidden bool) (map[uuid.UUID]int, error) {
+	ctx, cancel := s.withTimeout(ctx)
 	defer cancel()
 
 	const q = `
+SELECT parent_id, count(*)
+FROM comments
+WHERE parent_id = ANY($1) AND ($2 OR moderation_status <> 'hidden')
+GROUP BY parent_id;
+`
+	counts, err := s.queryCounts(ctx, q, ids, includeHidden)
+	if err != nil {
+		return nil, fmt.Errorf("count replies by comment ids: %w", err)
+	}
+	return counts, nil
+}
+
+func (s *Storage) CountCommentsByPostIDs(ctx context.Context, ids []uuid.UUID, includeHidden bool) (map[uuid.UUID]int, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	const q = `
+SELECT post_id, count(*)
+FROM comments
+WHERE post_id = ANY($1) AND ($2 OR moderation_status <> 'hidden')
+GROUP BY post_id;
+`
+	counts, err := s.queryCounts(ctx, q, ids, includeHidden)
+	if err != nil {
+		return nil, fmt.Errorf("count comments by post ids: %w", err)
+	}
+	return counts, nil
+}
+
+func (s *Storage) GetCommentTree(ctx context.Context, postID uuid.UUID, maxDepth, perNode int, includeHidden bool) ([]domain.Comment, error) {
+	if maxDepth <= 0 || perNode <= 0 {
+		return []domain.Comment{}, nil
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	// Every level after the first walks idx_comments_parent_created through
+	// a LATERAL subquery, so at most perNode replies are read per parent.
+	const q = `
+WITH RECURSIVE tree AS (
+    (SELECT ` + commentColumns + `, 1 AS depth
+     FROM comments
+     WHERE post_id = $1 AND parent_id IS NULL AND ($4 OR moderation_status <> 'hidden')
+     ORDER BY created_at, id
+     LIMIT $3)
+  UNION ALL
+    SELECT r.*, t.depth + 1
+    FROM tree t
+    CROSS JOIN LATERAL (
+        SELECT ` + commentColumns + `
+        FROM comments
+        WHERE parent_id = t.id AND ($4 OR moderation_status <> 'hidden')
+        ORDER BY created_at, id
+        LIMIT $3
+    ) r
+    WHERE t.depth < $2
+)
+SELECT ` + commentColumns + `
+FROM tree
+ORDER BY depth, created_at, id;
+`
+	out, err := s.queryComments(ctx, q, postID, maxDepth, perNode, includeHidden)
+	if err != nil {
+		return nil, fmt.Errorf("get comment tree: %w", err)
+	}
+	return out, nil
+}
+
+func (s *Storage) UpdateComment(ctx context.Context, c domain.Comment, editorID uuid.UUID) (*domain.Comment, error) {
+	if len(c.Content) > domain.MaxCommentLength {
+		return nil, domain.ErrCommentTooLong
+	}
+
+	editedAt := time.Now().Truncate(time.Microsecond)
+	if c.EditedAt != nil {
+		editedAt = *c.EditedAt
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	// The row lock keeps concurrent edits from recording the same previous
+	// content twice.
+	const qLock = `
+SELECT content, deleted_at IS NOT NULL
+FROM comments
+WHERE id = $1
+FOR UPDATE;
+`
+	const qRevision = `
+INSERT INTO comment_revisions (id, comment_id, content, editor_id, created_at)
+VALUES ($1, $2, $3, $4, $5);
+`
+	const qUpdate = `
 UPDATE comments
-SET content = $2
-WHERE id = $1;
+SET content = $2, edited_at = $3, revision_count = revision_count + 1
+WHERE id = $1
+RETURNING ` + commentColumns + `;
 `
-	tag, err := s.db.Exec(ctx, q, c.ID, c.Content)
+	var updated domain.Comment
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		var (
+			old     string
+			deleted bool
+		)
+		if err := tx.QueryRow(ctx, qLock, c.ID).Scan(&old, &deleted); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return domain.ErrCommentNotFound
+			}
+			return fmt.Errorf("lock comment: %w", err)
+		}
+		if deleted {
+			return domain.ErrCommentDeleted
+		}
+		// Version 7 IDs grow with time, which keeps edits made within the
+		// same timestamp in order.
+		if _, err := tx.Exec(ctx, qRevision, uuid.Must(uuid.NewV7()), c.ID, old, nullUUID(editorID), editedAt); err != nil {
+			return fmt.Errorf("record revision: %w", err)
+		}
+
+		var err error
+		updated, err = scanComment(tx.QueryRow(ctx, qUpdate, c.ID, c.Content, editedAt))
+		return err
+	})
 	if err != nil {
-		return fmt.Errorf("update comment: %w", err)
+		if domain.KindOf(err) != "" {
+			return nil, err
+		}
+		return nil, fmt.Errorf("update comment: %w", err)
 	}
-	if tag.RowsAffected() == 0 {
-		return ErrCommentNotFound
+	return &updated, nil
+}
+
+func (s *Storage) GetCommentRevisions(ctx context.Context, commentID uuid.UUID, p storage.PageParams) (*storage.RevisionPage, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	page := &storage.RevisionPage{Revisions: []domain.CommentRevision{}}
+
+	const qCount = `SELECT count(*) FROM comment_revisions WHERE comment_id = $1;`
+	if err := s.db.QueryRow(ctx, qCount, commentID).Scan(&page.TotalCount); err != nil {
+		return nil, fmt.Errorf("count revisions: %w", err)
 	}
-	return nil
+	if p.Limit() <= 0 {
+		return page, nil
+	}
+
+	tail, args := keyset([]string{"comment_id = $1"}, []any{commentID}, p)
+	q := `
+SELECT id, comment_id, content, editor_id, created_at
+FROM comment_revisions
+` + tail
+	rows, err := s.db.Query(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("get revisions: %w", err)
+	}
+	out, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.CommentRevision, error) {
+		var (
+			r      domain.CommentRevision
+			editor *uuid.UUID
+		)
+		err := row.Scan(&r.ID, &r.CommentID, &r.Content, &editor, &r.CreatedAt)
+		r.EditorID = fromNullUUID(editor)
+		return r, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get revisions: %w", err)
+	}
+
+	page.Revisions, page.HasNextPage, page.HasPreviousPage = trimPage(out, p)
+	return page, nil
 }
 
-func (s *Storage) DeleteComment(id uuid.UUID) error {
-	ctx, cancel := withTimeout()
+func (s *Storage) DeleteComment(ctx context.Context, id, deletedBy uuid.UUID) error {
+	ctx, cancel := s.withTimeout(ctx)
 	defer cancel()
 
-	const q = `DELETE FROM comments WHERE id = $1;`
-	tag, err := s.db.Exec(ctx, q, id)
+	const q = `
+UPDATE comments
+SET deleted_at = now(), deleted_by = $2
+WHERE id = $1 AND deleted_at IS NULL;
+`
+	tag, err := s.db.Exec(ctx, q, id, nullUUID(deletedBy))
 	if err != nil {
 		return fmt.Errorf("delete comment: %w", err)
 	}
-	if tag.RowsAffected() == 0 {
-		return ErrCommentNotFound
+	if tag.RowsAffected() > 0 {
+		return nil
 	}
-	return nil
+
+	const qExists = `SELECT EXISTS (SELECT 1 FROM comments WHERE id = $1);`
+	var exists bool
+	if err := s.db.QueryRow(ctx, qExists, id).Scan(&exists); err != nil {
+		return fmt.Errorf("delete comment: %w", err)
+	}
+	if !exists {
+		return domain.ErrCommentNotFound
+	}
+	return domain.ErrCommentDeleted
+}
+
+func (s *Storage) PurgeComment(ctx context.Context, id uuid.UUID) ([]uuid.UUID, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	// ON DELETE CASCADE would remove the replies as well; listing them
+	// returns their IDs.
+	const q = `
+WITH RECURSIVE thread AS (
+    SELECT id FROM comments WHERE id = $1
+  UNION ALL
+    SELECT c.id
+    FROM comments c
+    JOIN thread t ON c.parent_id = t.id
+)
+DELETE FROM comments
+WHERE id IN (SELECT id FROM thread)
+RETURNING id;
+`
+	rows, err := s.db.Query(ctx, q, id)
+	if err != nil {
+		return nil, fmt.Errorf("purge comment: %w", err)
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return nil, fmt.Errorf("purge comment: %w", err)
+	}
+	if len(ids) == 0 {
+		return nil, domain.ErrCommentNotFound
+	}
+	return ids, nil
 }
@@ -0,0 +1,53 @@
+package postgres
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"posts-comments-1/internal/config"
+	"posts-comments-1/internal/migrate"
+	"posts-comments-1/internal/storage"
+	"posts-comments-1/internal/storage/storagetest"
+	"posts-comments-1/migrations"
+)
+
+// newTestStorage connects to the database in TEST_POSTGRES_DSN and applies
+// the migrations, skipping the test when the variable is not set.
+func newTestStorage(t *testing.T) *Storage {
+	t.Helper()
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN is not set")
+	}
+
+	cfg := config.Default().Postgres
+	cfg.DSN = dsn
+	s, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+	t.Cleanup(s.Close)
+
+	m, err := migrate.New(s.Pool(), migrations.FS)
+	if err != nil {
+		t.Fatalf("migrate.New error: %v", err)
+	}
+	if _, err := m.Up(context.Background()); err != nil {
+		t.Fatalf("migrate Up error: %v", err)
+	}
+	return s
+}
+
+func TestStorage(t *testing.T) {
+	s := newTestStorage(t)
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		// Every other table refers to posts or users, or is truncated
+		// explicitly.
+		const q = `TRUNCATE posts, users, comment_filter_decisions CASCADE;`
+		if _, err := s.db.Exec(context.Background(), q); err != nil {
+			t.Fatalf("truncate: %v", err)
+		}
+		return s
+	})
+}
@@ -0,0 +1,77 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"posts-comments-1/internal/domain"
+)
+
+func (s *Storage) UpsertUser(ctx context.Context, u domain.User) error {
+	if u.CreatedAt.IsZero() {
+		u.CreatedAt = time.Now().Truncate(time.Microsecond)
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	const q = `
+INSERT INTO users (id, name, created_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (id) DO UPDATE
+SET name = COALESCE(NULLIF(EXCLUDED.name, ''), users.name);
+`
+	if _, err := s.db.Exec(ctx, q, u.ID, u.Name, u.CreatedAt); err != nil {
+		return fmt.Errorf("upsert user: %w", err)
+	}
+	return nil
+}
+
+func (s *Storage) GetUser(ctx context.Context, id uuid.UUID) (*domain.User, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	const q = `
+SELECT id, name, created_at
+FROM users
+WHERE id = $1;
+`
+	var u domain.User
+	err := s.db.QueryRow(ctx, q, id).Scan(&u.ID, &u.Name, &u.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrUserNotFound
+		}
+		return nil, fmt.Errorf("get user: %w", err)
+	}
+	return &u, nil
+}
+
+func (s *Storage) GetUsersByIDs(ctx context.Context, ids []uuid.UUID) ([]domain.User, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	const q = `
+SELECT id, name, created_at
+FROM users
+WHERE id = ANY($1);
+`
+	rows, err := s.db.Query(ctx, q, ids)
+	if err != nil {
+		return nil, fmt.Errorf("get users by ids: %w", err)
+	}
+	users, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.User, error) {
+		var u domain.User
+		err := row.Scan(&u.ID, &u.Name, &u.CreatedAt)
+		return u, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get users by ids: %w", err)
+	}
+	return users, nil
+}
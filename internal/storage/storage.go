@@ -1,20 +1,89 @@
 package storage
 
 import (
+	"context"
+	"time"
+
 	"github.com/google/uuid"
 	"posts-comments-1/internal/domain"
 )
 
 type Storage interface {
-	CreatePost(post domain.Post) error
-	GetPost(id uuid.UUID) (*domain.Post, error)
-	ListPosts(limit, offset int) ([]domain.Post, error)
-	UpdatePost(post domain.Post) error
-	DeletePost(id uuid.UUID) error
-
-	CreateComment(comment domain.Comment) error
-	GetComment(id uuid.UUID) (*domain.Comment, error)
-	GetComments(postID uuid.UUID, limit, offset int) ([]domain.Comment, error)
-	UpdateComment(comment domain.Comment) error
-	DeleteComment(id uuid.UUID) error
+	CreatePost(ctx context.Context, post domain.Post) error
+	GetPost(ctx context.Context, id uuid.UUID) (*domain.Post, error)
+	// GetPostsByIDs returns the posts with the given IDs in no particular
+	// order, leaving out missing ones.
+	GetPostsByIDs(ctx context.Context, ids []uuid.UUID) ([]domain.Post, error)
+	ListPosts(ctx context.Context, limit, offset int) ([]domain.Post, error)
+	ListPostsPage(ctx context.Context, p PageParams) (*PostPage, error)
+	UpdatePost(ctx context.Context, post domain.Post) error
+	DeletePost(ctx context.Context, id uuid.UUID) error
+
+	CreateComment(ctx context.Context, comment domain.Comment) error
+	GetComment(ctx context.Context, id uuid.UUID) (*domain.Comment, error)
+	// GetCommentsByIDs returns the comments with the given IDs in no
+	// particular order, leaving out missing ones.
+	GetCommentsByIDs(ctx context.Context, ids []uuid.UUID) ([]domain.Comment, error)
+	// Comment lists leave out comments with StatusHidden unless
+	// includeHidden is set.
+	GetComments(ctx context.Context, postID uuid.UUID, limit, offset int, includeHidden bool) ([]domain.Comment, error)
+	GetCommentsPage(ctx context.Context, postID uuid.UUID, p PageParams, includeHidden bool) (*CommentPage, error)
+	GetReplies(ctx context.Context, parentID uuid.UUID, p PageParams, includeHidden bool) (*CommentPage, error)
+	// Counts leave out hidden comments the same way unless includeHidden is
+	// set, so they match the TotalCount of the pages.
+	CountReplies(ctx context.Context, commentID uuid.UUID, includeHidden bool) (int, error)
+	// CountRepliesByCommentIDs returns the number of direct replies of every
+	// comment that has any.
+	CountRepliesByCommentIDs(ctx context.Context, ids []uuid.UUID, includeHidden bool) (map[uuid.UUID]int, error)
+	// CountCommentsByPostIDs returns the number of comments of every post
+	// that has any.
+	CountCommentsByPostIDs(ctx context.Context, ids []uuid.UUID, includeHidden bool) (map[uuid.UUID]int, error)
+	// GetCommentTree returns the comments of a post down to maxDepth levels,
+	// keeping at most perNode comments on every level under each parent
+	// (and at most perNode top-level comments). Parents precede children.
+	// Replies of hidden comments are left out with them.
+	GetCommentTree(ctx context.Context, postID uuid.UUID, maxDepth, perNode int, includeHidden bool) ([]domain.Comment, error)
+	// UpdateComment replaces the content of the comment, recording the
+	// previous content as a revision made by editorID at comment.EditedAt
+	// (or now), and returns the updated comment.
+	UpdateComment(ctx context.Context, comment domain.Comment, editorID uuid.UUID) (*domain.Comment, error)
+	// GetCommentRevisions returns the revisions of a comment, oldest first.
+	GetCommentRevisions(ctx context.Context, commentID uuid.UUID, p PageParams) (*RevisionPage, error)
+	// DeleteComment soft-deletes the comment on behalf of deletedBy. Its
+	// replies are kept; deleting it again fails with ErrCommentDeleted.
+	DeleteComment(ctx context.Context, id, deletedBy uuid.UUID) error
+	// PurgeComment permanently removes the comment with all its replies
+	// and their revisions, and returns the IDs of the removed comments.
+	PurgeComment(ctx context.Context, id uuid.UUID) ([]uuid.UUID, error)
+
+	// FlagComment records the flag and moves a visible comment to
+	// StatusPending.
+	FlagComment(ctx context.Context, flag domain.CommentFlag) error
+	// GetOpenFlags returns the unresolved flags, oldest first.
+	GetOpenFlags(ctx context.Context, p PageParams) (*FlagPage, error)
+	// ResolveFlag gives the comment of the flag the status chosen by
+	// resolvedBy, resolves every open flag of the comment and returns the
+	// updated comment.
+	ResolveFlag(ctx context.Context, flagID, resolvedBy uuid.UUID, status domain.ModerationStatus) (*domain.Comment, error)
+
+	// HasRecentComment reports whether authorID wrote a comment with the
+	// same content, ignoring case and surrounding spaces, at or after since
+	// that is not deleted.
+	HasRecentComment(ctx context.Context, authorID uuid.UUID, content string, since time.Time) (bool, error)
+	// RecordFilterDecisions stores the decisions of content filters for
+	// auditing.
+	RecordFilterDecisions(ctx context.Context, decisions []domain.FilterDecision) error
+
+	// UpsertUser creates the user or refreshes the name of an existing one.
+	UpsertUser(ctx context.Context, user domain.User) error
+	GetUser(ctx context.Context, id uuid.UUID) (*domain.User, error)
+	// GetUsersByIDs returns the users with the given IDs in no particular
+	// order, leaving out missing ones.
+	GetUsersByIDs(ctx context.Context, ids []uuid.UUID) ([]domain.User, error)
+}
+
+// HealthChecker is implemented by backends that depend on an external
+// service and can tell whether it is reachable.
+type HealthChecker interface {
+	Ping(ctx context.Context) error
 }
@@ -0,0 +1,762 @@
+// Package storagetest holds tests every storage.Storage implementation
+// must pass, run by the tests of each backend.
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"posts-comments-1/internal/domain"
+	"posts-comments-1/internal/storage"
+)
+
+// Run runs every test against a storage returned by newStorage, which must
+// be empty on each call.
+func Run(t *testing.T, newStorage func(t *testing.T) storage.Storage) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, s storage.Storage)
+	}{
+		{"CreateAndGetPost", createAndGetPost},
+		{"GetPost_NotFound", getPostNotFound},
+		{"ListPosts_Pagination", listPostsPagination},
+		{"ListPostsPage", listPostsPage},
+		{"UpdatePost_NotFound", updatePostNotFound},
+		{"DeletePost_NotFound", deletePostNotFound},
+		{"DeletePost_RemovesComments", deletePostRemovesComments},
+		{"CreateComment_TooLong", createCommentTooLong},
+		{"CreateComment_ParentNotFound", createCommentParentNotFound},
+		{"CreateComment_ParentWrongPost", createCommentParentWrongPost},
+		{"CreateComment_Disabled", createCommentDisabled},
+		{"GetComments_Pagination", getCommentsPagination},
+		{"GetCommentsPage_Keyset", getCommentsPageKeyset},
+		{"GetCommentTree_Limits", getCommentTreeLimits},
+		{"UpdateComment_NotFound", updateCommentNotFound},
+		{"UpdateComment_Revisions", updateCommentRevisions},
+		{"DeleteComment_NotFound", deleteCommentNotFound},
+		{"DeleteComment_KeepsReplies", deleteCommentKeepsReplies},
+		{"PurgeComment_RemovesThread", purgeCommentRemovesThread},
+		{"UpsertUser", upsertUser},
+		{"BatchLookups", batchLookups},
+		{"ResolveFlag_HidesComment", resolveFlagHidesComment},
+		{"HasRecentComment", hasRecentComment},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newStorage(t))
+		})
+	}
+}
+
+// now has the microsecond precision of postgres timestamps, so times read
+// back compare equal.
+func now() time.Time {
+	return time.Now().Truncate(time.Microsecond)
+}
+
+// newUser stores a user, as authors must exist in postgres.
+func newUser(t *testing.T, s storage.Storage) uuid.UUID {
+	t.Helper()
+	u := domain.User{ID: uuid.New(), Name: "user"}
+	if err := s.UpsertUser(context.Background(), u); err != nil {
+		t.Fatalf("UpsertUser error: %v", err)
+	}
+	return u.ID
+}
+
+// newPost returns a post by a new user, not stored yet.
+func newPost(t *testing.T, s storage.Storage) domain.Post {
+	t.Helper()
+	return domain.Post{
+		ID:              uuid.New(),
+		Title:           "t",
+		AuthorID:        newUser(t, s),
+		Content:         "c",
+		CreatedAt:       now(),
+		CommentsAllowed: true,
+	}
+}
+
+// newComment returns a comment on postID by a new user, not stored yet.
+func newComment(t *testing.T, s storage.Storage, postID uuid.UUID) domain.Comment {
+	t.Helper()
+	return domain.Comment{
+		ID:        uuid.New(),
+		PostID:    postID,
+		AuthorID:  newUser(t, s),
+		Content:   "hello",
+		CreatedAt: now(),
+	}
+}
+
+func createPost(t *testing.T, s storage.Storage, p domain.Post) {
+	t.Helper()
+	if err := s.CreatePost(context.Background(), p); err != nil {
+		t.Fatalf("CreatePost error: %v", err)
+	}
+}
+
+func createComment(t *testing.T, s storage.Storage, c domain.Comment) {
+	t.Helper()
+	if err := s.CreateComment(context.Background(), c); err != nil {
+		t.Fatalf("CreateComment error: %v", err)
+	}
+}
+
+func createAndGetPost(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	p := newPost(t, s)
+	p.ID = uuid.Nil
+	p.CreatedAt = time.Time{}
+	createPost(t, s, p)
+
+	posts, err := s.ListPosts(ctx, 10, 0)
+	if err != nil {
+		t.Fatalf("ListPosts error: %v", err)
+	}
+	if len(posts) != 1 {
+		t.Fatalf("expected 1 post, got %d", len(posts))
+	}
+
+	got, err := s.GetPost(ctx, posts[0].ID)
+	if err != nil {
+		t.Fatalf("GetPost error: %v", err)
+	}
+	if got.Title != p.Title || got.AuthorID != p.AuthorID {
+		t.Fatalf("unexpected post %+v", got)
+	}
+}
+
+func getPostNotFound(t *testing.T, s storage.Storage) {
+	if _, err := s.GetPost(context.Background(), uuid.New()); !errors.Is(err, domain.ErrPostNotFound) {
+		t.Fatalf("expected ErrPostNotFound, got %v", err)
+	}
+}
+
+func listPostsPagination(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	base := now()
+	for i := range 5 {
+		p := newPost(t, s)
+		p.CreatedAt = base.Add(time.Duration(i) * time.Second)
+		createPost(t, s, p)
+	}
+
+	for _, tt := range []struct{ offset, want int }{{0, 2}, {2, 2}, {4, 1}} {
+		page, err := s.ListPosts(ctx, 2, tt.offset)
+		if err != nil {
+			t.Fatalf("ListPosts error: %v", err)
+		}
+		if len(page) != tt.want {
+			t.Fatalf("offset %d: expected %d, got %d", tt.offset, tt.want, len(page))
+		}
+	}
+}
+
+func listPostsPage(t *testing.T, s storage.Storage) {
+	base := now()
+	for i := range 3 {
+		p := newPost(t, s)
+		p.CreatedAt = base.Add(time.Duration(i) * time.Second)
+		createPost(t, s, p)
+	}
+
+	page, err := s.ListPostsPage(context.Background(), storage.PageParams{Last: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Posts) != 2 || !page.HasPreviousPage || page.HasNextPage || page.TotalCount != 3 {
+		t.Fatalf("unexpected page: %+v", page)
+	}
+	if !page.Posts[0].CreatedAt.Before(page.Posts[1].CreatedAt) {
+		t.Fatalf("expected ascending order within page")
+	}
+}
+
+func updatePostNotFound(t *testing.T, s storage.Storage) {
+	err := s.UpdatePost(context.Background(), domain.Post{ID: uuid.New()})
+	if !errors.Is(err, domain.ErrPostNotFound) {
+		t.Fatalf("expected ErrPostNotFound, got %v", err)
+	}
+}
+
+func deletePostNotFound(t *testing.T, s storage.Storage) {
+	if err := s.DeletePost(context.Background(), uuid.New()); !errors.Is(err, domain.ErrPostNotFound) {
+		t.Fatalf("expected ErrPostNotFound, got %v", err)
+	}
+}
+
+func deletePostRemovesComments(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	p := newPost(t, s)
+	createPost(t, s, p)
+	root := newComment(t, s, p.ID)
+	reply := newComment(t, s, p.ID)
+	reply.ParentID = &root.ID
+	createComment(t, s, root)
+	createComment(t, s, reply)
+
+	if err := s.DeletePost(ctx, p.ID); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []uuid.UUID{root.ID, reply.ID} {
+		if _, err := s.GetComment(ctx, id); !errors.Is(err, domain.ErrCommentNotFound) {
+			t.Fatalf("expected ErrCommentNotFound, got %v", err)
+		}
+	}
+}
+
+func createCommentTooLong(t *testing.T, s storage.Storage) {
+	p := newPost(t, s)
+	createPost(t, s, p)
+
+	c := newComment(t, s, p.ID)
+	c.Content = strings.Repeat("a", domain.MaxCommentLength+1)
+	if err := s.CreateComment(context.Background(), c); !errors.Is(err, domain.ErrCommentTooLong) {
+		t.Fatalf("expected ErrCommentTooLong, got %v", err)
+	}
+}
+
+func createCommentParentNotFound(t *testing.T, s storage.Storage) {
+	p := newPost(t, s)
+	createPost(t, s, p)
+
+	parentID := uuid.New()
+	c := newComment(t, s, p.ID)
+	c.ParentID = &parentID
+	if err := s.CreateComment(context.Background(), c); !errors.Is(err, domain.ErrParentCommentNotFound) {
+		t.Fatalf("expected ErrParentCommentNotFound, got %v", err)
+	}
+}
+
+func createCommentParentWrongPost(t *testing.T, s storage.Storage) {
+	p1, p2 := newPost(t, s), newPost(t, s)
+	createPost(t, s, p1)
+	createPost(t, s, p2)
+
+	parent := newComment(t, s, p1.ID)
+	createComment(t, s, parent)
+
+	child := newComment(t, s, p2.ID)
+	child.ParentID = &parent.ID
+	if err := s.CreateComment(context.Background(), child); !errors.Is(err, domain.ErrParentCommentWrongPost) {
+		t.Fatalf("expected ErrParentCommentWrongPost, got %v", err)
+	}
+}
+
+func createCommentDisabled(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	p := newPost(t, s)
+	p.CommentsAllowed = false
+	createPost(t, s, p)
+	if err := s.CreateComment(ctx, newComment(t, s, p.ID)); !errors.Is(err, domain.ErrCommentsDisabled) {
+		t.Fatalf("expected ErrCommentsDisabled, got %v", err)
+	}
+
+	// Anonymous posts and comments have no author.
+	anon := domain.Post{ID: uuid.New(), Title: "t", Content: "c"}
+	createPost(t, s, anon)
+	err := s.CreateComment(ctx, domain.Comment{PostID: anon.ID, Content: "hello"})
+	if !errors.Is(err, domain.ErrCommentsDisabled) {
+		t.Fatalf("expected ErrCommentsDisabled, got %v", err)
+	}
+}
+
+func getCommentsPagination(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	p := newPost(t, s)
+	createPost(t, s, p)
+	base := now()
+	for i := range 5 {
+		c := newComment(t, s, p.ID)
+		c.CreatedAt = base.Add(time.Duration(i) * time.Second)
+		createComment(t, s, c)
+	}
+
+	for _, tt := range []struct{ offset, want int }{{0, 2}, {2, 2}, {4, 1}} {
+		page, err := s.GetComments(ctx, p.ID, 2, tt.offset, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page) != tt.want {
+			t.Fatalf("offset %d: expected %d, got %d", tt.offset, tt.want, len(page))
+		}
+	}
+}
+
+func getCommentsPageKeyset(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	p := newPost(t, s)
+	createPost(t, s, p)
+	base := now()
+	for i := range 5 {
+		c := newComment(t, s, p.ID)
+		c.CreatedAt = base.Add(time.Duration(i) * time.Second)
+		createComment(t, s, c)
+	}
+
+	page1, err := s.GetCommentsPage(ctx, p.ID, storage.PageParams{First: 2}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page1.Comments) != 2 || !page1.HasNextPage || page1.HasPreviousPage || page1.TotalCount != 5 {
+		t.Fatalf("unexpected first page: %+v", page1)
+	}
+
+	// A comment arriving during paging must not shift the next page.
+	late := newComment(t, s, p.ID)
+	late.CreatedAt = base.Add(-time.Hour)
+	createComment(t, s, late)
+
+	after := storage.CommentCursor(page1.Comments[1])
+	page2, err := s.GetCommentsPage(ctx, p.ID, storage.PageParams{First: 10, After: &after}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page2.Comments) != 3 || page2.HasNextPage || !page2.HasPreviousPage {
+		t.Fatalf("unexpected second page: %+v", page2)
+	}
+	if !page2.Comments[0].CreatedAt.Equal(base.Add(2 * time.Second)) {
+		t.Fatalf("expected page to continue after cursor, got %v", page2.Comments[0].CreatedAt)
+	}
+
+	before := storage.CommentCursor(page2.Comments[0])
+	back, err := s.GetCommentsPage(ctx, p.ID, storage.PageParams{Last: 2, Before: &before}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(back.Comments) != 2 || !back.HasPreviousPage || !back.HasNextPage {
+		t.Fatalf("unexpected backward page: %+v", back)
+	}
+	if back.Comments[1].ID != page1.Comments[1].ID {
+		t.Fatalf("expected backward page to end at %v, got %v", page1.Comments[1].ID, back.Comments[1].ID)
+	}
+}
+
+func getCommentTreeLimits(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	p := newPost(t, s)
+	createPost(t, s, p)
+	base := now()
+	reply := func(parent *uuid.UUID, i int) domain.Comment {
+		c := newComment(t, s, p.ID)
+		c.ParentID = parent
+		c.CreatedAt = base.Add(time.Duration(i) * time.Second)
+		createComment(t, s, c)
+		return c
+	}
+
+	root := reply(nil, 0)
+	reply(nil, 1)
+	reply(nil, 2)
+	child := reply(&root.ID, 3)
+	reply(&root.ID, 4)
+	reply(&root.ID, 5)
+	reply(&child.ID, 6)
+
+	tree, err := s.GetCommentTree(ctx, p.ID, 2, 2, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 2 roots, 2 replies of the first root, grandchildren cut by maxDepth.
+	if len(tree) != 4 {
+		t.Fatalf("expected 4 comments, got %d", len(tree))
+	}
+	if tree[0].ID != root.ID || tree[2].ID != child.ID {
+		t.Fatalf("expected parents before replies, got %+v", tree)
+	}
+	if tree, _ := s.GetCommentTree(ctx, p.ID, 3, 10, false); len(tree) != 7 {
+		t.Fatalf("expected the whole tree of 7 comments, got %d", len(tree))
+	}
+
+	n, err := s.CountReplies(ctx, root.ID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Fatalf("expected 3 replies, got %d", n)
+	}
+
+	replies, err := s.GetReplies(ctx, root.ID, storage.PageParams{First: 2}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replies.Comments) != 2 || !replies.HasNextPage || replies.TotalCount != 3 {
+		t.Fatalf("unexpected replies page: %+v", replies)
+	}
+}
+
+func updateCommentNotFound(t *testing.T, s storage.Storage) {
+	c := domain.Comment{ID: uuid.New(), Content: "x"}
+	if _, err := s.UpdateComment(context.Background(), c, uuid.Nil); !errors.Is(err, domain.ErrCommentNotFound) {
+		t.Fatalf("expected ErrCommentNotFound, got %v", err)
+	}
+}
+
+func updateCommentRevisions(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	p := newPost(t, s)
+	createPost(t, s, p)
+	c := newComment(t, s, p.ID)
+	createComment(t, s, c)
+
+	editor := newUser(t, s)
+	base := now()
+	for i, content := range []string{"second", "third"} {
+		edit := c
+		edit.Content = content
+		editedAt := base.Add(time.Duration(i) * time.Second)
+		edit.EditedAt = &editedAt
+		got, err := s.UpdateComment(ctx, edit, editor)
+		if err != nil {
+			t.Fatalf("UpdateComment error: %v", err)
+		}
+		if got.Content != content || got.RevisionCount != i+1 || got.EditedAt == nil || !got.EditedAt.Equal(editedAt) {
+			t.Fatalf("unexpected updated comment %+v", got)
+		}
+	}
+
+	page, err := s.GetCommentRevisions(ctx, c.ID, storage.PageParams{First: 10})
+	if err != nil {
+		t.Fatalf("GetCommentRevisions error: %v", err)
+	}
+	if len(page.Revisions) != 2 || page.TotalCount != 2 || page.HasNextPage {
+		t.Fatalf("unexpected revisions page: %+v", page)
+	}
+	// Revisions keep the previous content, oldest first.
+	if page.Revisions[0].Content != "hello" || page.Revisions[1].Content != "second" || page.Revisions[0].EditorID != editor {
+		t.Fatalf("unexpected revisions %+v", page.Revisions)
+	}
+
+	after := storage.RevisionCursor(page.Revisions[0])
+	next, err := s.GetCommentRevisions(ctx, c.ID, storage.PageParams{First: 10, After: &after})
+	if err != nil {
+		t.Fatalf("GetCommentRevisions error: %v", err)
+	}
+	if len(next.Revisions) != 1 || next.Revisions[0].ID != page.Revisions[1].ID || !next.HasPreviousPage {
+		t.Fatalf("unexpected page after the first revision: %+v", next)
+	}
+}
+
+func deleteCommentNotFound(t *testing.T, s storage.Storage) {
+	if err := s.DeleteComment(context.Background(), uuid.New(), uuid.New()); !errors.Is(err, domain.ErrCommentNotFound) {
+		t.Fatalf("expected ErrCommentNotFound, got %v", err)
+	}
+}
+
+func deleteCommentKeepsReplies(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	p := newPost(t, s)
+	createPost(t, s, p)
+	root := newComment(t, s, p.ID)
+	reply := newComment(t, s, p.ID)
+	reply.ParentID = &root.ID
+	createComment(t, s, root)
+	createComment(t, s, reply)
+
+	moderator := newUser(t, s)
+	if err := s.DeleteComment(ctx, root.ID, moderator); err != nil {
+		t.Fatalf("DeleteComment error: %v", err)
+	}
+	got, err := s.GetComment(ctx, root.ID)
+	if err != nil {
+		t.Fatalf("GetComment error: %v", err)
+	}
+	if !got.Deleted() || got.DeletedBy != moderator {
+		t.Fatalf("expected a tombstone deleted by %v, got %+v", moderator, got)
+	}
+	if page, _ := s.GetReplies(ctx, root.ID, storage.PageParams{First: 10}, false); len(page.Comments) != 1 {
+		t.Fatalf("expected the reply to stay, got %+v", page.Comments)
+	}
+	if page, _ := s.GetCommentsPage(ctx, p.ID, storage.PageParams{First: 10}, false); page.TotalCount != 2 {
+		t.Fatalf("expected the tombstone to stay listed, got %d comments", page.TotalCount)
+	}
+
+	if err := s.DeleteComment(ctx, root.ID, moderator); !errors.Is(err, domain.ErrCommentDeleted) {
+		t.Fatalf("expected ErrCommentDeleted, got %v", err)
+	}
+	if _, err := s.UpdateComment(ctx, *got, moderator); !errors.Is(err, domain.ErrCommentDeleted) {
+		t.Fatalf("expected ErrCommentDeleted, got %v", err)
+	}
+	another := newComment(t, s, p.ID)
+	another.ParentID = &root.ID
+	if err := s.CreateComment(ctx, another); !errors.Is(err, domain.ErrParentCommentDeleted) {
+		t.Fatalf("expected ErrParentCommentDeleted, got %v", err)
+	}
+}
+
+func purgeCommentRemovesThread(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	p := newPost(t, s)
+	createPost(t, s, p)
+	root, sibling := newComment(t, s, p.ID), newComment(t, s, p.ID)
+	reply := newComment(t, s, p.ID)
+	reply.ParentID = &root.ID
+	nested := newComment(t, s, p.ID)
+	nested.ParentID = &reply.ID
+	for _, c := range []domain.Comment{root, sibling, reply, nested} {
+		createComment(t, s, c)
+	}
+	// Revisions and flags go with the purged comments.
+	edit := reply
+	edit.Content = "edited"
+	if _, err := s.UpdateComment(ctx, edit, uuid.Nil); err != nil {
+		t.Fatalf("UpdateComment error: %v", err)
+	}
+	if err := s.FlagComment(ctx, domain.CommentFlag{ID: uuid.New(), CommentID: nested.ID, Reason: "spam"}); err != nil {
+		t.Fatalf("FlagComment error: %v", err)
+	}
+
+	ids, err := s.PurgeComment(ctx, reply.ID)
+	if err != nil {
+		t.Fatalf("PurgeComment error: %v", err)
+	}
+	if len(ids) != 2 {
+		t.Fatalf("expected the reply and its reply to be purged, got %v", ids)
+	}
+	for _, id := range ids {
+		if _, err := s.GetComment(ctx, id); !errors.Is(err, domain.ErrCommentNotFound) {
+			t.Fatalf("expected ErrCommentNotFound, got %v", err)
+		}
+	}
+	if n, _ := s.CountReplies(ctx, root.ID, false); n != 0 {
+		t.Fatalf("expected no replies left, got %d", n)
+	}
+	if comments, _ := s.GetComments(ctx, p.ID, 10, 0, false); len(comments) != 2 {
+		t.Fatalf("expected 2 comments left, got %d", len(comments))
+	}
+	if page, _ := s.GetCommentRevisions(ctx, reply.ID, storage.PageParams{First: 10}); page.TotalCount != 0 {
+		t.Fatalf("expected the revisions to be purged, got %d", page.TotalCount)
+	}
+	if page, _ := s.GetOpenFlags(ctx, storage.PageParams{First: 10}); page.TotalCount != 0 {
+		t.Fatalf("expected the flags to be purged, got %d", page.TotalCount)
+	}
+
+	if _, err := s.PurgeComment(ctx, reply.ID); !errors.Is(err, domain.ErrCommentNotFound) {
+		t.Fatalf("expected ErrCommentNotFound, got %v", err)
+	}
+}
+
+func upsertUser(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	id := uuid.New()
+
+	if _, err := s.GetUser(ctx, id); !errors.Is(err, domain.ErrUserNotFound) {
+		t.Fatalf("expected ErrUserNotFound, got %v", err)
+	}
+
+	if err := s.UpsertUser(ctx, domain.User{ID: id, Name: "alice"}); err != nil {
+		t.Fatalf("UpsertUser error: %v", err)
+	}
+	// An identity without a name must not erase the stored one.
+	if err := s.UpsertUser(ctx, domain.User{ID: id}); err != nil {
+		t.Fatalf("UpsertUser error: %v", err)
+	}
+
+	u, err := s.GetUser(ctx, id)
+	if err != nil {
+		t.Fatalf("GetUser error: %v", err)
+	}
+	if u.Name != "alice" {
+		t.Fatalf("expected name %q, got %q", "alice", u.Name)
+	}
+}
+
+func batchLookups(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	p1, p2 := newPost(t, s), newPost(t, s)
+	createPost(t, s, p1)
+	createPost(t, s, p2)
+	root := newComment(t, s, p1.ID)
+	reply := newComment(t, s, p1.ID)
+	reply.ParentID = &root.ID
+	createComment(t, s, root)
+	createComment(t, s, reply)
+
+	missing := uuid.New()
+	posts, err := s.GetPostsByIDs(ctx, []uuid.UUID{p1.ID, missing, p2.ID})
+	if err != nil {
+		t.Fatalf("GetPostsByIDs error: %v", err)
+	}
+	if len(posts) != 2 {
+		t.Fatalf("expected 2 posts, got %d", len(posts))
+	}
+	comments, err := s.GetCommentsByIDs(ctx, []uuid.UUID{reply.ID, missing})
+	if err != nil {
+		t.Fatalf("GetCommentsByIDs error: %v", err)
+	}
+	if len(comments) != 1 || comments[0].ID != reply.ID {
+		t.Fatalf("unexpected comments %+v", comments)
+	}
+	users, err := s.GetUsersByIDs(ctx, []uuid.UUID{root.AuthorID, missing})
+	if err != nil {
+		t.Fatalf("GetUsersByIDs error: %v", err)
+	}
+	if len(users) != 1 || users[0].ID != root.AuthorID {
+		t.Fatalf("unexpected users %+v", users)
+	}
+
+	counts, err := s.CountCommentsByPostIDs(ctx, []uuid.UUID{p1.ID, p2.ID}, false)
+	if err != nil {
+		t.Fatalf("CountCommentsByPostIDs error: %v", err)
+	}
+	if counts[p1.ID] != 2 || counts[p2.ID] != 0 {
+		t.Fatalf("unexpected comment counts %v", counts)
+	}
+	replies, err := s.CountRepliesByCommentIDs(ctx, []uuid.UUID{root.ID, reply.ID}, false)
+	if err != nil {
+		t.Fatalf("CountRepliesByCommentIDs error: %v", err)
+	}
+	if replies[root.ID] != 1 || replies[reply.ID] != 0 {
+		t.Fatalf("unexpected reply counts %v", replies)
+	}
+}
+
+func resolveFlagHidesComment(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	p := newPost(t, s)
+	createPost(t, s, p)
+	c, other := newComment(t, s, p.ID), newComment(t, s, p.ID)
+	createComment(t, s, c)
+	createComment(t, s, other)
+
+	reporter := newUser(t, s)
+	first := domain.CommentFlag{ID: uuid.New(), CommentID: c.ID, ReporterID: reporter, Reason: "spam", CreatedAt: now()}
+	second := domain.CommentFlag{ID: uuid.New(), CommentID: c.ID, Reason: "rude", CreatedAt: now().Add(time.Second)}
+	for _, f := range []domain.CommentFlag{first, second} {
+		if err := s.FlagComment(ctx, f); err != nil {
+			t.Fatalf("FlagComment error: %v", err)
+		}
+	}
+	if got, _ := s.GetComment(ctx, c.ID); got.Status != domain.StatusPending {
+		t.Fatalf("expected a pending comment, got %q", got.Status)
+	}
+	queue, err := s.GetOpenFlags(ctx, storage.PageParams{First: 10})
+	if err != nil {
+		t.Fatalf("GetOpenFlags error: %v", err)
+	}
+	if len(queue.Flags) != 2 || queue.Flags[0].ID != first.ID || queue.Flags[0].ReporterID != reporter {
+		t.Fatalf("expected both flags oldest first, got %+v", queue.Flags)
+	}
+
+	if _, err := s.ResolveFlag(ctx, uuid.New(), uuid.Nil, domain.StatusHidden); !errors.Is(err, domain.ErrFlagNotFound) {
+		t.Fatalf("expected ErrFlagNotFound, got %v", err)
+	}
+	if _, err := s.ResolveFlag(ctx, first.ID, uuid.Nil, domain.StatusPending); !errors.Is(err, domain.ErrInvalidResolution) {
+		t.Fatalf("expected ErrInvalidResolution, got %v", err)
+	}
+	got, err := s.ResolveFlag(ctx, first.ID, uuid.Nil, domain.StatusHidden)
+	if err != nil {
+		t.Fatalf("ResolveFlag error: %v", err)
+	}
+	if got.Status != domain.StatusHidden {
+		t.Fatalf("expected a hidden comment, got %q", got.Status)
+	}
+	if page, _ := s.GetOpenFlags(ctx, storage.PageParams{First: 10}); page.TotalCount != 0 {
+		t.Fatalf("expected every flag of the comment to be resolved, got %d open", page.TotalCount)
+	}
+	if _, err := s.ResolveFlag(ctx, second.ID, uuid.Nil, domain.StatusVisible); !errors.Is(err, domain.ErrFlagResolved) {
+		t.Fatalf("expected ErrFlagResolved, got %v", err)
+	}
+
+	if comments, _ := s.GetComments(ctx, p.ID, 10, 0, false); len(comments) != 1 || comments[0].ID != other.ID {
+		t.Fatalf("expected the hidden comment to be left out, got %+v", comments)
+	}
+	if page, _ := s.GetCommentsPage(ctx, p.ID, storage.PageParams{First: 10}, true); page.TotalCount != 2 {
+		t.Fatalf("expected 2 comments with hidden ones, got %d", page.TotalCount)
+	}
+	for includeHidden, want := range map[bool]int{false: 1, true: 2} {
+		counts, err := s.CountCommentsByPostIDs(ctx, []uuid.UUID{p.ID}, includeHidden)
+		if err != nil {
+			t.Fatalf("CountCommentsByPostIDs error: %v", err)
+		}
+		if counts[p.ID] != want {
+			t.Fatalf("expected %d comments with includeHidden=%v, got %d", want, includeHidden, counts[p.ID])
+		}
+		if tree, _ := s.GetCommentTree(ctx, p.ID, 1, 10, includeHidden); len(tree) != want {
+			t.Fatalf("expected a tree of %d comments with includeHidden=%v, got %d", want, includeHidden, len(tree))
+		}
+	}
+}
+
+// hasRecentComment checks that duplicates are found regardless of case and
+// of the surrounding whitespace strings.TrimSpace removes.
+func hasRecentComment(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	p := newPost(t, s)
+	createPost(t, s, p)
+	author := newUser(t, s)
+	comment := func(content string) domain.Comment {
+		c := newComment(t, s, p.ID)
+		c.AuthorID = author
+		c.Content = content
+		createComment(t, s, c)
+		return c
+	}
+	hello := comment("Hello")
+	comment("\n\tBye \u3000")
+
+	since := hello.CreatedAt.Add(-time.Minute)
+	for _, content := range []string{"hello", " hello ", "\thello\r\n", "\v\fHELLO\u0085", "\u00a0hello\u2003", "bye", " Bye\n"} {
+		if ok, err := s.HasRecentComment(ctx, author, content, since); err != nil || !ok {
+			t.Fatalf("%q: expected the comment to be found, got %v, %v", content, ok, err)
+		}
+	}
+	for _, content := range []string{"hel lo", "hello!", "good bye"} {
+		if ok, _ := s.HasRecentComment(ctx, author, content, since); ok {
+			t.Fatalf("%q: expected no comment to be found", content)
+		}
+	}
+	if ok, _ := s.HasRecentComment(ctx, uuid.New(), "hello", since); ok {
+		t.Fatal("expected comments of other authors to be ignored")
+	}
+	if ok, _ := s.HasRecentComment(ctx, author, "hello", time.Now().Add(time.Minute)); ok {
+		t.Fatal("expected comments before since to be ignored")
+	}
+	if err := s.DeleteComment(ctx, hello.ID, uuid.Nil); err != nil {
+		t.Fatalf("DeleteComment error: %v", err)
+	}
+	if ok, _ := s.HasRecentComment(ctx, author, "hello", since); ok {
+		t.Fatal("expected deleted comments to be ignored")
+	}
+
+	// Comments stored out of CreatedAt order are still found by time.
+	old := newComment(t, s, p.ID)
+	old.AuthorID = author
+	old.Content = "old"
+	old.CreatedAt = hello.CreatedAt.Add(-time.Hour)
+	createComment(t, s, old)
+	if ok, _ := s.HasRecentComment(ctx, author, "old", old.CreatedAt); !ok {
+		t.Fatal("expected the older comment to be found")
+	}
+	if ok, _ := s.HasRecentComment(ctx, author, "old", old.CreatedAt.Add(time.Second)); ok {
+		t.Fatal("expected the older comment to be before since")
+	}
+	if ok, _ := s.HasRecentComment(ctx, author, "bye", old.CreatedAt); !ok {
+		t.Fatal("expected newer comments to be found after the older one")
+	}
+	if _, err := s.PurgeComment(ctx, old.ID); err != nil {
+		t.Fatalf("PurgeComment error: %v", err)
+	}
+	if ok, _ := s.HasRecentComment(ctx, author, "old", old.CreatedAt); ok {
+		t.Fatal("expected purged comments to be ignored")
+	}
+}
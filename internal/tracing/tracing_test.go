@@ -0,0 +1,61 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"posts-comments-1/internal/config"
+)
+
+func TestMiddleware_ContinuesTrace(t *testing.T) {
+	if _, err := Setup(context.Background(), config.TracingConfig{Exporter: "none"}); err != nil {
+		t.Fatalf("Setup error: %v", err)
+	}
+	rec := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+
+	var inner trace.SpanContext
+	h := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		inner = trace.SpanContextFromContext(r.Context())
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/query", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := rec.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+	if got := span.SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("expected the incoming trace, got %s", got)
+	}
+	if span.Parent().SpanID().String() != "00f067aa0ba902b7" {
+		t.Fatalf("unexpected parent %s", span.Parent().SpanID())
+	}
+	if inner.SpanID() != span.SpanContext().SpanID() {
+		t.Fatal("handler does not run in the server span")
+	}
+}
+
+func TestOperationName(t *testing.T) {
+	cases := map[string]string{
+		"\nSELECT 1":           "SELECT",
+		"with t as (select 1)": "WITH",
+		"":                     "query",
+	}
+	for sql, want := range cases {
+		if got := operationName(sql); got != want {
+			t.Fatalf("operationName(%q) = %q, want %q", sql, got, want)
+		}
+	}
+}
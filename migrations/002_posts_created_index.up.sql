@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS idx_posts_created
+  ON posts (created_at, id);
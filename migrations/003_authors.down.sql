@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_comments_author_created;
+DROP INDEX IF EXISTS idx_posts_author_created;
+
+ALTER TABLE comments DROP COLUMN IF EXISTS author_id;
+ALTER TABLE posts DROP COLUMN IF EXISTS author_id;
+
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS users (
+  id UUID PRIMARY KEY,
+  name TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE posts
+  ADD COLUMN IF NOT EXISTS author_id UUID NULL REFERENCES users(id);
+
+ALTER TABLE comments
+  ADD COLUMN IF NOT EXISTS author_id UUID NULL REFERENCES users(id);
+
+CREATE INDEX IF NOT EXISTS idx_posts_author_created
+  ON posts (author_id, created_at, id);
+
+CREATE INDEX IF NOT EXISTS idx_comments_author_created
+  ON comments (author_id, created_at, id);
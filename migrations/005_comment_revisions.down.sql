@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_comment_revisions_comment_created;
+
+DROP TABLE IF EXISTS comment_revisions;
+
+ALTER TABLE comments DROP COLUMN IF EXISTS revision_count;
+ALTER TABLE comments DROP COLUMN IF EXISTS edited_at;
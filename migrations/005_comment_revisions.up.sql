@@ -0,0 +1,14 @@
+ALTER TABLE comments
+  ADD COLUMN IF NOT EXISTS edited_at TIMESTAMPTZ NULL,
+  ADD COLUMN IF NOT EXISTS revision_count INT NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS comment_revisions (
+  id UUID PRIMARY KEY,
+  comment_id UUID NOT NULL REFERENCES comments(id) ON DELETE CASCADE,
+  content VARCHAR(2000) NOT NULL,
+  editor_id UUID NULL REFERENCES users(id),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_comment_revisions_comment_created
+  ON comment_revisions (comment_id, created_at, id);
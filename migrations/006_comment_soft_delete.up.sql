@@ -0,0 +1,3 @@
+ALTER TABLE comments
+  ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ NULL,
+  ADD COLUMN IF NOT EXISTS deleted_by UUID NULL REFERENCES users(id);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_comment_flags_open_comment;
+DROP INDEX IF EXISTS idx_comment_flags_open_created;
+
+DROP TABLE IF EXISTS comment_flags;
+
+ALTER TABLE comments DROP COLUMN IF EXISTS moderation_status;
@@ -0,0 +1,20 @@
+ALTER TABLE comments
+  ADD COLUMN IF NOT EXISTS moderation_status TEXT NOT NULL DEFAULT 'visible'
+    CHECK (moderation_status IN ('visible', 'pending', 'hidden', 'removed'));
+
+CREATE TABLE IF NOT EXISTS comment_flags (
+  id UUID PRIMARY KEY,
+  comment_id UUID NOT NULL REFERENCES comments(id) ON DELETE CASCADE,
+  reporter_id UUID NULL REFERENCES users(id),
+  reason VARCHAR(500) NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  resolved_at TIMESTAMPTZ NULL,
+  resolved_by UUID NULL REFERENCES users(id),
+  resolution TEXT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_comment_flags_open_created
+  ON comment_flags (created_at, id) WHERE resolved_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_comment_flags_open_comment
+  ON comment_flags (comment_id) WHERE resolved_at IS NULL;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_comment_filter_decisions_created;
+
+DROP TABLE IF EXISTS comment_filter_decisions;
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS comment_filter_decisions (
+  id UUID PRIMARY KEY,
+  -- Rejected comments are never stored, so comment_id has no foreign key.
+  comment_id UUID NOT NULL,
+  post_id UUID NOT NULL,
+  author_id UUID NULL,
+  filter TEXT NOT NULL,
+  action TEXT NOT NULL CHECK (action IN ('reject', 'flag', 'rewrite')),
+  reason TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_comment_filter_decisions_created
+  ON comment_filter_decisions (created_at, id);
@@ -0,0 +1,8 @@
+// Package migrations embeds the numbered SQL migrations of the PostgreSQL
+// schema. Every version has an NNN_name.up.sql and an NNN_name.down.sql file.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS